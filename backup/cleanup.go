@@ -23,6 +23,7 @@ import (
 type cleanupHandler interface {
 	MarkUploadSuccess(ref paranoid.File)
 	MarkUploadFailure(ref paranoid.File)
+	MarkExcluded(ref paranoid.File)
 	MarkProspectFailure()
 	MarkManifestUploadFailure()
 	MarkManifestUploadSuccess()
@@ -40,6 +41,9 @@ func (ch *snapshotCleanupHandler) MarkUploadSuccess(ref paranoid.File) {
 func (ch *snapshotCleanupHandler) MarkUploadFailure(ref paranoid.File) {
 }
 
+func (ch *snapshotCleanupHandler) MarkExcluded(ref paranoid.File) {
+}
+
 func (ch *snapshotCleanupHandler) MarkProspectFailure() {
 }
 
@@ -55,6 +59,7 @@ func (ch *snapshotCleanupHandler) Execute() error {
 
 type incrementalCleanupHandler struct {
 	uploadedFiles            []paranoid.File
+	excludedFiles            []paranoid.File
 	sawProspectFailure       bool
 	sawUploadFailure         bool
 	sawManifestUploadFailure bool
@@ -69,6 +74,10 @@ func (ch *incrementalCleanupHandler) MarkUploadFailure(ref paranoid.File) {
 	ch.sawUploadFailure = true
 }
 
+func (ch *incrementalCleanupHandler) MarkExcluded(ref paranoid.File) {
+	ch.excludedFiles = append(ch.excludedFiles, ref)
+}
+
 func (ch *incrementalCleanupHandler) MarkProspectFailure() {
 	ch.sawProspectFailure = true
 }
@@ -82,6 +91,15 @@ func (ch *incrementalCleanupHandler) MarkManifestUploadSuccess() {
 }
 
 func (ch *incrementalCleanupHandler) Execute() error {
+	// Excluded files will never be uploaded, so they don't need to wait for a successful backup.
+	excludedErr := ch.remove(ch.excludedFiles, "excluded")
+	if err := ch.removeUploaded(); err != nil {
+		return err
+	}
+	return excludedErr
+}
+
+func (ch *incrementalCleanupHandler) removeUploaded() error {
 	lgr := zap.S()
 	if ch.sawProspectFailure {
 		lgr.Infow("skipping_incremental_cleanup", "reason", "prospect_failure")
@@ -99,10 +117,18 @@ func (ch *incrementalCleanupHandler) Execute() error {
 		lgr.Infow("skipping_incremental_cleanup", "reason", "manifest_not_uploaded")
 		return nil
 	}
+	return ch.remove(ch.uploadedFiles, "uploaded")
+}
+
+func (ch *incrementalCleanupHandler) remove(files []paranoid.File, kind string) error {
+	lgr := zap.S()
+	if len(files) == 0 {
+		return nil
+	}
 	if *noCleanIncremental {
-		lgr.Infow("skipping_incremental_cleanup", "reason", "not_enabled", "would_remove", len(ch.uploadedFiles))
+		lgr.Infow("skipping_incremental_cleanup", "reason", "not_enabled", "kind", kind, "would_remove", len(files))
 		if *verboseClean {
-			for _, ref := range ch.uploadedFiles {
+			for _, ref := range files {
 				lgr.Infow("cleanup_would_have_removed_file", "name", ref.Name())
 			}
 		}
@@ -110,7 +136,7 @@ func (ch *incrementalCleanupHandler) Execute() error {
 	}
 
 	var lastErr error
-	for _, ref := range ch.uploadedFiles {
+	for _, ref := range files {
 		if err := ref.Delete(); err != nil {
 			lgr.Errorw("cleanup_failed_to_remove_file", "name", ref.Name(), "err", err)
 			lastErr = err
@@ -0,0 +1,101 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/retailnext/cassandrabackup/paranoid"
+)
+
+func TestIncrementalCleanupExcluded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	newFile := func(name string) paranoid.File {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		file, err := paranoid.NewFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+	remaining := func() []string {
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, info := range infos {
+			names = append(names, info.Name())
+		}
+		return names
+	}
+
+	// A failed upload keeps uploaded files around for the next run, but excluded files go anyway.
+	ch := &incrementalCleanupHandler{}
+	ch.MarkUploadSuccess(newFile("uploaded"))
+	ch.MarkUploadFailure(newFile("failed"))
+	ch.MarkExcluded(newFile("excluded"))
+	if err := ch.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(remaining(), []string{"failed", "uploaded"}); diff != nil {
+		t.Fatal(diff)
+	}
+
+	ch = &incrementalCleanupHandler{}
+	ch.MarkUploadSuccess(newFile("uploaded"))
+	ch.MarkExcluded(newFile("excluded"))
+	ch.MarkManifestUploadSuccess()
+	if err := ch.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(remaining(), []string{"failed"}); diff != nil {
+		t.Fatal(diff)
+	}
+}
+
+func TestReadExcludeFile(t *testing.T) {
+	file, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.WriteString("# scratch tables\nanalytics\n\n  ks.scratch_*  \nre:ks\\.tmp[0-9]+\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	patterns, err := readExcludeFile(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(patterns, []string{"analytics", "ks.scratch_*", `re:ks\.tmp[0-9]+`}); diff != nil {
+		t.Fatal(diff)
+	}
+}
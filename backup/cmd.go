@@ -23,8 +23,13 @@ var (
 	_ = Cmd.Command("snapshot", "Make a snapshot backup.")
 	_ = Cmd.Command("run", "Make incremental and snapshot backups on a schedule. (Foreground Daemon)")
 
+	commitLogCmd  = Cmd.Command("commitlog", "Upload a commitlog segment. Usable as archive_command in commitlog_archiving.properties.")
+	commitLogPath = commitLogCmd.Arg("path", "The commitlog segment to upload (%path)").Required().String()
+
 	overrideCluster    = Cmd.Flag("cluster", "Override cluster name when storing backups.").String()
 	overrideHostname   = Cmd.Flag("hostname", "Override hostname when storing backups.").String()
 	noCleanIncremental = Cmd.Flag("no-clean-incremental", "Do not clean up incremental backup files.").Bool()
 	verboseClean       = Cmd.Flag("verbose-clean", "Log incremental backup files that are or would be removed.").Bool()
+	excludeTables      = Cmd.Flag("exclude", "Don't back up tables matching these patterns (keyspace, keyspace.table, globs, or re:<regexp>)").Strings()
+	excludeFile        = Cmd.Flag("exclude-file", "Read more --exclude patterns from this file, one per line.").String()
 )
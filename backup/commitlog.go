@@ -0,0 +1,117 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/retailnext/cassandrabackup/bucket"
+	"github.com/retailnext/cassandrabackup/digest"
+	"github.com/retailnext/cassandrabackup/manifests"
+	"github.com/retailnext/cassandrabackup/nodeidentity"
+	"github.com/retailnext/cassandrabackup/paranoid"
+	"go.uber.org/zap"
+)
+
+// DoCommitLog uploads a single commitlog segment, for use as Cassandra's archive_command.
+// It doesn't connect to Cassandra, since it's run by Cassandra itself, possibly during startup or shutdown.
+func DoCommitLog(ctx context.Context) error {
+	lgr := zap.S()
+	identity, manifest, err := nodeidentity.GetIdentityAndManifestTemplateOffline(overrideCluster, overrideHostname)
+	if err != nil {
+		return err
+	}
+	manifest.ManifestType = manifests.ManifestTypeCommitLog
+
+	file, err := paranoid.NewFile(*commitLogPath)
+	if err != nil {
+		return err
+	}
+	digests, err := digest.GetUncached(ctx, file)
+	if err != nil {
+		return err
+	}
+	client := bucket.OpenShared()
+	if err := client.PutBlob(ctx, file, digests); err != nil && err != bucket.UploadSkipped {
+		return err
+	}
+
+	if err := addCommitLog(ctx, client, identity, manifest, file, digests); err != nil {
+		return err
+	}
+	lgr.Infow("archived_commitlog", "name", filepath.Base(file.Name()), "size", file.Len(), "manifest", manifest.Key())
+	return nil
+}
+
+// addCommitLog adds a segment to the commitlog manifest for manifest.Time.
+// Segments archived within the same second share a manifest, so archiving them concurrently would lose
+// some of them if the manifest weren't updated under a lock on the commitlog directory.
+func addCommitLog(ctx context.Context, client *bucket.Client, identity manifests.NodeIdentity, manifest manifests.Manifest, file paranoid.File, digests digest.ForUpload) error {
+	unlock, err := lockDirectory(filepath.Dir(file.Name()))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	manifest.DataFiles = make(map[string]digest.ForRestore)
+	manifest.DataFileInfo = make(map[string]manifests.FileInfo)
+	keys, err := client.ListManifests(ctx, identity, manifest.Time, manifest.Time+1)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if key != manifest.Key() {
+			continue
+		}
+		existing, err := client.GetManifests(ctx, identity, manifests.ManifestKeys{key})
+		if err != nil {
+			return err
+		}
+		for name, segment := range existing[0].DataFiles {
+			manifest.DataFiles[name] = segment
+		}
+		for name, info := range existing[0].DataFileInfo {
+			manifest.DataFileInfo[name] = info
+		}
+	}
+
+	name := filepath.Base(file.Name())
+	manifest.DataFiles[name] = digests.ForRestore()
+	manifest.DataFileInfo[name] = manifests.NewFileInfo(file)
+	return client.PutManifest(ctx, identity, manifest)
+}
+
+// lockDirectory takes an exclusive flock on dir, which is held until the returned func is called.
+func lockDirectory(dir string) (func(), error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		if closeErr := f.Close(); closeErr != nil {
+			panic(closeErr)
+		}
+		return nil, err
+	}
+	return func() {
+		// Closing the descriptor releases the lock.
+		if closeErr := f.Close(); closeErr != nil {
+			panic(closeErr)
+		}
+	}, nil
+}
@@ -0,0 +1,121 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+	"github.com/retailnext/cassandrabackup/bucket"
+	"github.com/retailnext/cassandrabackup/digest"
+	"github.com/retailnext/cassandrabackup/manifests"
+	"github.com/retailnext/cassandrabackup/paranoid"
+)
+
+// slowBackend widens the window between reading and rewriting a manifest.
+type slowBackend struct {
+	*bucket.MemoryBackend
+}
+
+func (b slowBackend) GetDocument(ctx context.Context, key string) ([]byte, error) {
+	document, err := b.MemoryBackend.GetDocument(ctx, key)
+	time.Sleep(time.Millisecond)
+	return document, err
+}
+
+func TestAddCommitLogConcurrently(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	identity := manifests.NodeIdentity{
+		Cluster:  "test-cluster",
+		Hostname: "test-host",
+	}
+	manifest := manifests.Manifest{
+		Time:         1,
+		ManifestType: manifests.ManifestTypeCommitLog,
+	}
+	client := bucket.NewClient(slowBackend{bucket.NewMemoryBackend()}, "")
+
+	type segment struct {
+		file    paranoid.File
+		digests digest.ForUpload
+	}
+	var expected []string
+	var segments []segment
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("CommitLog-7-%d.log", i)
+		expected = append(expected, name)
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		file, err := paranoid.NewFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		digests, err := digest.GetUncached(ctx, file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		segments = append(segments, segment{file, digests})
+	}
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	errs := make(chan error, len(segments))
+	for _, s := range segments {
+		wg.Add(1)
+		go func(s segment) {
+			defer wg.Done()
+			<-start
+			errs <- addCommitLog(ctx, client, identity, manifest, s.file, s.digests)
+		}(s)
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stored, err := client.GetManifests(ctx, identity, manifests.ManifestKeys{manifest.Key()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual []string
+	for name := range stored[0].DataFiles {
+		actual = append(actual, name)
+	}
+	sort.Strings(actual)
+	sort.Strings(expected)
+	if diff := deep.Equal(actual, expected); diff != nil {
+		t.Fatal(diff)
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"bufio"
+	"os"
+	"strings"
+
+	"github.com/retailnext/cassandrabackup/manifests"
+)
+
+// tableFilter selects the tables to back up according to --exclude and --exclude-file.
+func tableFilter() (manifests.TableFilter, error) {
+	exclude := append([]string(nil), *excludeTables...)
+	if *excludeFile != "" {
+		fromFile, err := readExcludeFile(*excludeFile)
+		if err != nil {
+			return manifests.TableFilter{}, err
+		}
+		exclude = append(exclude, fromFile...)
+	}
+	return manifests.NewTableFilter(nil, exclude, true)
+}
+
+// readExcludeFile reads one pattern per line, ignoring blank lines and lines starting with #.
+func readExcludeFile(name string) ([]string, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			panic(closeErr)
+		}
+	}()
+
+	var patterns []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, scanner.Err()
+}
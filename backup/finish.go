@@ -32,7 +32,9 @@ func (p *processor) finish() error {
 	}()
 
 	p.manifest.DataFiles = make(map[string]digest.ForRestore)
+	p.manifest.DataFileInfo = make(map[string]manifests.FileInfo)
 	var hadFailures bool
+	var excluded int
 	var prospectError, uploadError error
 	for {
 		record, ok := <-p.uploadedFiles
@@ -48,6 +50,11 @@ func (p *processor) finish() error {
 			}
 			continue
 		}
+		if record.Excluded {
+			p.cleanupHandler.MarkExcluded(record.File)
+			excluded++
+			continue
+		}
 		if record.UploadError != nil && record.UploadError != bucket.UploadSkipped {
 			p.cleanupHandler.MarkUploadFailure(record.File)
 			lgr.Errorw("upload_error", "path", record.File.Name(), "err", record.UploadError)
@@ -66,9 +73,14 @@ func (p *processor) finish() error {
 			lgr.Panicw("duplicate_manifest_path", "record", record)
 		}
 		p.manifest.DataFiles[record.ManifestPath] = record.Digests.ForRestore()
+		p.manifest.DataFileInfo[record.ManifestPath] = manifests.NewFileInfo(record.File)
 		p.cleanupHandler.MarkUploadSuccess(record.File)
 	}
 
+	if excluded > 0 {
+		lgr.Infow("excluded_files", "files", excluded, "patterns", p.manifest.ExcludedTables)
+	}
+
 	if hadFailures {
 		// Still write a manifest for the stuff we did manage to upload.
 		p.manifest.ManifestType = manifests.ManifestTypeIncomplete
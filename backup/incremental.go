@@ -30,6 +30,11 @@ func DoIncremental(ctx context.Context) error {
 	}
 
 	manifest.ManifestType = manifests.ManifestTypeIncremental
+	tables, err := tableFilter()
+	if err != nil {
+		return err
+	}
+	manifest.ExcludedTables = tables.Exclusions()
 
 	pr := &processor{
 		ctx: ctx,
@@ -40,10 +45,13 @@ func DoIncremental(ctx context.Context) error {
 		prospectedFiles: make(chan fileRecord, 1),
 		uploadedFiles:   make(chan fileRecord, 1),
 
+		root: dataPath,
+
 		identity:       identity,
 		manifest:       manifest,
 		cleanupHandler: &incrementalCleanupHandler{},
 		pathProcessor:  incrementalPathProcessor{},
+		tables:         tables,
 	}
 
 	go pr.prospect()
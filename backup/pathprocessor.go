@@ -24,7 +24,6 @@ type pathProcessor interface {
 }
 
 type incrementalPathProcessor struct {
-	ignoreKeyspaces map[string]struct{}
 }
 
 func (p incrementalPathProcessor) ManifestPath(dataRelPath string) string {
@@ -33,11 +32,6 @@ func (p incrementalPathProcessor) ManifestPath(dataRelPath string) string {
 		return ""
 	}
 
-	// This is just for development
-	if _, ignore := p.ignoreKeyspaces[parts[0]]; ignore {
-		return ""
-	}
-
 	restoreParts := make([]string, 0, len(parts)-1)
 	restoreParts = append(restoreParts, parts[0:2]...)
 	restoreParts = append(restoreParts, parts[3:]...)
@@ -32,16 +32,22 @@ type processor struct {
 	prospectedFiles chan fileRecord
 	uploadedFiles   chan fileRecord
 
+	// root is the cassandra data directory.
+	root string
+
 	identity       manifests.NodeIdentity
 	manifest       manifests.Manifest
 	cleanupHandler cleanupHandler
 	pathProcessor  pathProcessor
+	tables         manifests.TableFilter
 }
 
 type fileRecord struct {
 	ManifestPath string
 	File         paranoid.File
 	Digests      digest.ForUpload
+	// Excluded files are passed along only so they can be cleaned up.
+	Excluded bool
 
 	ProspectError error
 	UploadError   error
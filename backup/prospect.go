@@ -19,6 +19,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/retailnext/cassandrabackup/manifests"
 	"github.com/retailnext/cassandrabackup/paranoid"
 	"go.uber.org/zap"
 )
@@ -28,7 +29,7 @@ const dataPath = "/var/lib/cassandra/data"
 func (p *processor) prospect() {
 	defer close(p.prospectedFiles)
 
-	records, walkErr := getFiles(dataPath, p.pathProcessor)
+	records, walkErr := getFiles(p.root, p.pathProcessor, p.tables)
 	if walkErr != nil {
 		p.prospectedFiles <- fileRecord{
 			ProspectError: walkErr,
@@ -38,7 +39,9 @@ func (p *processor) prospect() {
 
 	doneCh := p.ctx.Done()
 	for _, record := range records {
-		record.Digests, record.ProspectError = p.digestCache.Get(p.ctx, record.File)
+		if !record.Excluded {
+			record.Digests, record.ProspectError = p.digestCache.Get(p.ctx, record.File)
+		}
 
 		select {
 		case <-doneCh:
@@ -51,14 +54,14 @@ func (p *processor) prospect() {
 	}
 }
 
-func getFiles(root string, pathProcessor pathProcessor) ([]fileRecord, error) {
+func getFiles(root string, pathProcessor pathProcessor, tables manifests.TableFilter) ([]fileRecord, error) {
 	lgr := zap.S()
 
 	var records []fileRecord
 
 	walkErr := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
-			if isIgnorableWalkError(dataPath, path, err) {
+			if isIgnorableWalkError(root, path, err) {
 				// This is something we can ignore, like a non-snapshot non-backup file disappearing mid-walk.
 				lgr.Debugw("ignoring_walk_error", "path", path, "err", err)
 				return nil
@@ -76,13 +79,14 @@ func getFiles(root string, pathProcessor pathProcessor) ([]fileRecord, error) {
 			File: paranoid.NewFileFromInfo(path, info),
 		}
 
-		relPath, err := filepath.Rel(dataPath, path)
+		relPath, err := filepath.Rel(root, path)
 		if err != nil {
 			panic(err)
 		}
 		record.ManifestPath = pathProcessor.ManifestPath(relPath)
 		if record.ManifestPath != "" {
 			// The processor has indicated that this file should not be backed up.
+			record.Excluded = !tables.MatchPath(filepath.ToSlash(record.ManifestPath))
 			records = append(records, record)
 		}
 
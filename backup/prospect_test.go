@@ -0,0 +1,191 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/retailnext/cassandrabackup/bucket"
+	"github.com/retailnext/cassandrabackup/cache"
+	"github.com/retailnext/cassandrabackup/digest"
+	"github.com/retailnext/cassandrabackup/manifests"
+)
+
+func TestGetFilesExclusions(t *testing.T) {
+	root, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	for _, name := range []string{
+		"luneta/site-bcfbb16bdd5b36ac9db83d20236eb7ee/backups/md-1-big-Data.db",
+		"luneta/site-bcfbb16bdd5b36ac9db83d20236eb7ee/backups/.site_subscription_uuid_index/md-1-big-Data.db",
+		"luneta/sites-bcfbb16bdd5b36ac9db83d20236eb7ee/backups/md-1-big-Data.db",
+		"luneta/sites-bcfbb16bdd5b36ac9db83d20236eb7ee/md-2-big-Data.db",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tables, err := manifests.NewTableFilter(nil, []string{"luneta.site"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := getFiles(root, incrementalPathProcessor{}, tables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	excluded := make(map[string]bool)
+	for _, record := range records {
+		excluded[record.ManifestPath] = record.Excluded
+	}
+	expected := map[string]bool{
+		"luneta/site-bcfbb16bdd5b36ac9db83d20236eb7ee/md-1-big-Data.db":                               true,
+		"luneta/site-bcfbb16bdd5b36ac9db83d20236eb7ee/.site_subscription_uuid_index/md-1-big-Data.db": true,
+		"luneta/sites-bcfbb16bdd5b36ac9db83d20236eb7ee/md-1-big-Data.db":                              false,
+	}
+	if diff := deep.Equal(excluded, expected); diff != nil {
+		t.Fatal(diff)
+	}
+}
+
+func TestIncrementalExclusions(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	root := filepath.Join(dir, "data")
+	for _, name := range []string{
+		"luneta/site-bcfbb16bdd5b36ac9db83d20236eb7ee/backups/md-1-big-Data.db",
+		"luneta/sites-bcfbb16bdd5b36ac9db83d20236eb7ee/backups/md-1-big-Data.db",
+		"luneta/sites-bcfbb16bdd5b36ac9db83d20236eb7ee/backups/md-1-big-Index.db",
+		"scratch/things-bcfbb16bdd5b36ac9db83d20236eb7ee/backups/md-1-big-Data.db",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	storage, err := cache.Open(filepath.Join(dir, "cache.db"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if closeErr := storage.Close(); closeErr != nil {
+			panic(closeErr)
+		}
+	}()
+
+	patterns := []string{"luneta.site", "scratch"}
+	tables, err := manifests.NewTableFilter(nil, patterns, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity := manifests.NodeIdentity{
+		Cluster:  "test-cluster",
+		Hostname: "test-host",
+	}
+	bucketClient := bucket.NewClient(bucket.NewMemoryBackend(), "")
+	pr := &processor{
+		ctx: ctx,
+
+		bucketClient: bucketClient,
+		digestCache:  digest.NewCache(storage),
+
+		prospectedFiles: make(chan fileRecord, 1),
+		uploadedFiles:   make(chan fileRecord, 1),
+
+		root: root,
+
+		identity: identity,
+		manifest: manifests.Manifest{
+			Time:           1,
+			ManifestType:   manifests.ManifestTypeIncremental,
+			ExcludedTables: tables.Exclusions(),
+		},
+		cleanupHandler: &incrementalCleanupHandler{},
+		pathProcessor:  incrementalPathProcessor{},
+		tables:         tables,
+	}
+
+	go pr.prospect()
+	go pr.uploadFiles()
+	if err := pr.finish(); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := bucketClient.ListManifests(ctx, identity, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := bucketClient.GetManifests(ctx, identity, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 1 {
+		t.Fatalf("expected one manifest, got %d", len(stored))
+	}
+	manifest := stored[0]
+	if diff := deep.Equal(manifest.ExcludedTables, patterns); diff != nil {
+		t.Error(diff)
+	}
+
+	var referenced []string
+	for path := range manifest.DataFiles {
+		referenced = append(referenced, path)
+	}
+	sort.Strings(referenced)
+	if diff := deep.Equal(referenced, []string{
+		"luneta/sites-bcfbb16bdd5b36ac9db83d20236eb7ee/md-1-big-Data.db",
+		"luneta/sites-bcfbb16bdd5b36ac9db83d20236eb7ee/md-1-big-Index.db",
+	}); diff != nil {
+		t.Error(diff)
+	}
+
+	var uploaded, expected []string
+	if err := bucketClient.ListBlobs(ctx, func(digests digest.ForRestore) bool {
+		uploaded = append(uploaded, digests.URLSafe())
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, digests := range manifest.DataFiles {
+		expected = append(expected, digests.URLSafe())
+	}
+	sort.Strings(uploaded)
+	sort.Strings(expected)
+	if diff := deep.Equal(uploaded, expected); diff != nil {
+		t.Error(diff)
+	}
+}
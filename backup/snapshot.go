@@ -23,6 +23,8 @@ import (
 	"github.com/retailnext/cassandrabackup/manifests"
 	"github.com/retailnext/cassandrabackup/nodeidentity"
 	"github.com/retailnext/cassandrabackup/nodetool"
+	"github.com/retailnext/cassandrabackup/systemlocal"
+	"go.uber.org/zap"
 )
 
 func DoSnapshotBackup(ctx context.Context) error {
@@ -30,6 +32,14 @@ func DoSnapshotBackup(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	tables, err := tableFilter()
+	if err != nil {
+		return err
+	}
+	manifest.ExcludedTables = tables.Exclusions()
+
+	bucketClient := bucket.OpenShared()
+	manifest.Schema = uploadSchema(ctx, bucketClient, manifest.Address)
 
 	snapshotName := fmt.Sprintf("auto-%s", manifest.Time.Decimal())
 	err = nodetool.TakeSnapshot(snapshotName)
@@ -42,12 +52,14 @@ func DoSnapshotBackup(ctx context.Context) error {
 	pr := &processor{
 		ctx: ctx,
 
-		bucketClient: bucket.OpenShared(),
+		bucketClient: bucketClient,
 		digestCache:  digest.OpenShared(),
 
 		prospectedFiles: make(chan fileRecord),
 		uploadedFiles:   make(chan fileRecord),
 
+		root: dataPath,
+
 		identity: identity,
 		manifest: manifest,
 
@@ -57,9 +69,28 @@ func DoSnapshotBackup(ctx context.Context) error {
 		pathProcessor: snapshotPathProcessor{
 			name: snapshotName,
 		},
+		tables: tables,
 	}
 
 	go pr.prospect()
 	go pr.uploadFiles()
 	return pr.finish()
 }
+
+// uploadSchema returns the ID of the uploaded schema, or an empty string if it couldn't be
+// backed up. That shouldn't prevent backing up the data.
+func uploadSchema(ctx context.Context, bucketClient *bucket.Client, address string) string {
+	lgr := zap.S()
+	schema, err := systemlocal.GetSchema(address)
+	if err != nil {
+		lgr.Errorw("get_schema_error", "addr", address, "err", err)
+		return ""
+	}
+	id, err := bucketClient.PutSchema(ctx, []byte(schema.CQL()))
+	if err != nil {
+		lgr.Errorw("put_schema_error", "err", err)
+		return ""
+	}
+	lgr.Infow("uploaded_schema", "schema", id, "keyspaces", len(schema.Keyspaces))
+	return id
+}
@@ -49,7 +49,7 @@ func (p *processor) uploadFile(record fileRecord, wg *sync.WaitGroup, limiter <-
 		wg.Done()
 	}()
 
-	if record.ProspectError != nil {
+	if record.ProspectError != nil || record.Excluded {
 		return
 	}
 
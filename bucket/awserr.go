@@ -21,6 +21,9 @@ import (
 )
 
 func IsNoSuchKey(err error) bool {
+	if err == NotFound {
+		return true
+	}
 	if err != nil {
 		if awsErr, ok := err.(awserr.Error); ok {
 			switch awsErr.Code() {
@@ -0,0 +1,88 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bucket
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/retailnext/cassandrabackup/digest"
+	"github.com/retailnext/cassandrabackup/paranoid"
+)
+
+var NotFound = errors.New("object not found")
+
+// Backend is the storage that a Client keeps blobs and manifest documents in.
+// Keys are "/" separated and already include the Client's prefix.
+type Backend interface {
+	PutBlob(ctx context.Context, key string, file paranoid.File, digests digest.ForUpload) error
+	// DownloadBlob replaces the contents of file with the blob. Verification is left to the caller.
+	DownloadBlob(ctx context.Context, key string, file *os.File) error
+	// OpenBlob streams the blob, or returns NotFound. Verification is left to the caller.
+	OpenBlob(ctx context.Context, key string) (io.ReadCloser, error)
+	// HeadBlob returns NotFound if there is no such blob.
+	HeadBlob(ctx context.Context, key string) (BlobInfo, error)
+
+	// PutDocument stores a gzip compressed JSON document, which may have been encrypted.
+	PutDocument(ctx context.Context, key string, document []byte) error
+	// GetDocument returns a document stored with PutDocument, or NotFound.
+	// The result may or may not still be gzip compressed.
+	GetDocument(ctx context.Context, key string) ([]byte, error)
+
+	// List calls fn with pages of keys and common prefixes ("/" delimited) directly under prefix,
+	// in lexical order, starting after startAfter. Listing stops early if fn returns false.
+	List(ctx context.Context, prefix, startAfter string, fn func(keys, prefixes []string) bool) error
+
+	// Delete removes a blob or document. Deleting something that does not exist is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// retentionExtender is implemented by backends that lock blobs for a configured retention period.
+type retentionExtender interface {
+	// BlobRetention returns how long new blobs are locked for, or zero if they are not.
+	BlobRetention() time.Duration
+	ExtendBlobRetention(ctx context.Context, key string, until time.Time) error
+}
+
+// versionedBackend is implemented by backends that keep deleted and overwritten objects as noncurrent
+// versions, like S3 buckets with versioning (which object lock requires).
+type versionedBackend interface {
+	// ListVersions calls fn with pages of every version and delete marker of the keys under prefix.
+	// Versions are grouped by key, newest first. Listing stops early if fn returns false.
+	ListVersions(ctx context.Context, prefix string, fn func(versions []ObjectVersion) bool) error
+	// VersionRetainUntil returns when the object lock on a version expires, or zero if it isn't locked.
+	VersionRetainUntil(ctx context.Context, key, versionID string) (time.Time, error)
+	// DeleteVersion permanently removes one version or delete marker.
+	DeleteVersion(ctx context.Context, key, versionID string) error
+}
+
+type ObjectVersion struct {
+	Key          string
+	VersionID    string
+	IsLatest     bool
+	DeleteMarker bool
+	Size         int64
+	LastModified time.Time
+}
+
+type BlobInfo struct {
+	ContentLength int64
+	DeleteMarker  bool
+	LastModified  time.Time
+	RetainUntil   time.Time
+}
@@ -19,16 +19,19 @@ import (
 	"errors"
 	"io"
 	"os"
+	"time"
 
-	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/retailnext/cassandrabackup/digest"
+	"github.com/retailnext/cassandrabackup/envelope"
 	"github.com/retailnext/cassandrabackup/paranoid"
 	"go.uber.org/zap"
 )
 
 var UploadSkipped = errors.New("upload skipped")
 
+const objectLockExtensionSlack = 24 * time.Hour
+
 func (c *Client) PutBlob(ctx context.Context, file paranoid.File, digests digest.ForUpload) error {
 	key := c.absoluteKeyForBlob(digests.ForRestore())
 	if exists, err := c.blobExists(ctx, digests); err != nil {
@@ -40,7 +43,13 @@ func (c *Client) PutBlob(ctx context.Context, file paranoid.File, digests digest
 		return UploadSkipped
 	}
 
-	if err := c.uploader.UploadFile(ctx, key, file, digests); err != nil {
+	var err error
+	if c.encryption != nil {
+		err = c.putEncryptedBlob(ctx, key, file)
+	} else {
+		err = c.backend.PutBlob(ctx, key, file, digests)
+	}
+	if err != nil {
 		uploadErrors.Inc()
 		if ctxErr := ctx.Err(); ctxErr != nil {
 			return ctxErr
@@ -54,32 +63,91 @@ func (c *Client) PutBlob(ctx context.Context, file paranoid.File, digests digest
 
 func (c *Client) DownloadBlob(ctx context.Context, digests digest.ForRestore, file *os.File) error {
 	key := c.absoluteKeyForBlob(digests)
-	getObjectInput := &s3.GetObjectInput{
-		Bucket: &c.bucket,
-		Key:    &key,
-	}
-	attempts := 0
-	for {
-		if _, err := file.Seek(0, io.SeekStart); err != nil {
-			zap.S().Panicw("get_blob_seek_error", "err", err)
+	if c.encryption != nil {
+		if err := c.downloadEncryptedBlob(ctx, key, file); err != nil {
+			return err
 		}
-		if err := file.Truncate(0); err != nil {
-			zap.S().Panicw("get_blob_truncate_error", "err", err)
+	} else {
+		if err := c.backend.DownloadBlob(ctx, key, file); err != nil {
+			return err
 		}
-		_, err := c.downloader.DownloadWithContext(ctx, file, getObjectInput)
+	}
+	return digests.Verify(ctx, file)
+}
+
+// OpenBlob streams the (decrypted) contents of a blob. The caller is responsible for verifying them.
+func (c *Client) OpenBlob(ctx context.Context, digests digest.ForRestore) (io.ReadCloser, error) {
+	key := c.absoluteKeyForBlob(digests)
+	if c.encryption != nil {
+		return c.openEncryptedBlob(ctx, key)
+	}
+	return c.backend.OpenBlob(ctx, key)
+}
+
+// ListBlobs calls fn with the digest of every blob in the bucket, stopping early if fn returns false.
+func (c *Client) ListBlobs(ctx context.Context, fn func(digests digest.ForRestore) bool) error {
+	stopped := false
+	var walk func(prefix string) error
+	walk = func(prefix string) error {
+		var subPrefixes []string
+		err := c.backend.List(ctx, prefix, "", func(keys, prefixes []string) bool {
+			for _, key := range keys {
+				if digests, ok := c.decodeBlobKey(key); ok {
+					if !fn(digests) {
+						stopped = true
+						return false
+					}
+				} else {
+					zap.S().Warnw("unexpected_objects_in_bucket", "keys", []string{key})
+				}
+			}
+			subPrefixes = append(subPrefixes, prefixes...)
+			return true
+		})
 		if err != nil {
-			attempts++
-			if ctxErr := ctx.Err(); ctxErr != nil {
-				return ctxErr
+			return err
+		}
+		for _, subPrefix := range subPrefixes {
+			if stopped {
+				return nil
 			}
-			if IsNoSuchKey(err) || attempts > getBlobRetriesLimit {
+			if err := walk(subPrefix); err != nil {
 				return err
 			}
-			zap.S().Errorw("get_blob_s3_error", "err", err, "attempts", attempts)
-		} else {
-			return digests.Verify(ctx, file)
 		}
+		return nil
+	}
+	return walk(c.absoluteKeyPrefixForBlobs())
+}
+
+// StatBlob returns NotFound if there is no blob for digests.
+func (c *Client) StatBlob(ctx context.Context, digests digest.ForRestore) (BlobInfo, error) {
+	return c.backend.HeadBlob(ctx, c.absoluteKeyForBlob(digests))
+}
+
+func (c *Client) DeleteBlob(ctx context.Context, digests digest.ForRestore) error {
+	return c.backend.Delete(ctx, c.absoluteKeyForBlob(digests))
+}
+
+// StoredLength returns the size of the blob stored for a file of plaintextLength bytes.
+func (c *Client) StoredLength(plaintextLength int64) int64 {
+	if c.encryption != nil {
+		return envelope.CiphertextLength(plaintextLength)
 	}
+	return plaintextLength
+}
+
+// ObjectLockEnabled reports whether new and reused blobs are locked against deletion.
+func (c *Client) ObjectLockEnabled() bool {
+	extender, ok := c.backend.(retentionExtender)
+	return ok && extender.BlobRetention() > 0
+}
+
+// PlausibleStoredLength reports whether a blob of storedLength bytes could hold a file of
+// plaintextLength bytes. With encryption enabled, blobs uploaded before it was are still
+// stored in plaintext, and restore passes them through.
+func (c *Client) PlausibleStoredLength(plaintextLength, storedLength int64) bool {
+	return storedLength == c.StoredLength(plaintextLength) || storedLength == plaintextLength
 }
 
 func (c *Client) blobExists(ctx context.Context, digests digest.ForUpload) (bool, error) {
@@ -88,38 +156,62 @@ func (c *Client) blobExists(ctx context.Context, digests digest.ForUpload) (bool
 		return true, nil
 	}
 
-	headObjectInput := &s3.HeadObjectInput{
-		Bucket: &c.bucket,
-		Key:    &key,
-	}
-	headObjectOutput, err := c.s3Svc.HeadObjectWithContext(ctx, headObjectInput)
+	info, err := c.backend.HeadBlob(ctx, key)
 	if err != nil {
-		if IsNoSuchKey(err) {
+		if err == NotFound {
 			return false, nil
 		}
-		if ctxErr := ctx.Err(); ctxErr != nil {
-			return false, ctxErr
-		}
 		return false, err
 	}
-	if headObjectOutput.DeleteMarker != nil && *headObjectOutput.DeleteMarker {
+	if info.DeleteMarker {
 		zap.S().Infow("blob_exists_saw_delete_marker", "key", key)
 		return false, nil
 	}
-	expectedLength := digests.ContentLength()
-	actualLength := *headObjectOutput.ContentLength
+	expectedLength := c.StoredLength(digests.ContentLength())
+	actualLength := info.ContentLength
 	if actualLength != expectedLength {
 		zap.S().Infow("blob_exists_saw_wrong_length", "key", key, "expected", expectedLength, "actual", actualLength)
 		return false, nil
 	}
 
-	if headObjectOutput.ObjectLockRetainUntilDate != nil {
-		c.existsCache.Put(digests.ForRestore(), *headObjectOutput.ObjectLockRetainUntilDate)
+	retainUntil, err := c.extendRetention(ctx, key, info.RetainUntil)
+	if err != nil {
+		return false, err
+	}
+	if !retainUntil.IsZero() {
+		c.existsCache.Put(digests.ForRestore(), retainUntil)
 	}
 
 	return true, nil
 }
 
+// extendRetention makes sure an existing blob stays locked at least as long as a new upload would be,
+// so that it outlives every manifest referencing it. It returns the resulting retain until date.
+func (c *Client) extendRetention(ctx context.Context, key string, retainUntil time.Time) (time.Time, error) {
+	extender, ok := c.backend.(retentionExtender)
+	if !ok {
+		return retainUntil, nil
+	}
+	retention := extender.BlobRetention()
+	if retention <= 0 {
+		return retainUntil, nil
+	}
+	now := time.Now()
+	if retainUntil.After(now.Add(retention)) {
+		return retainUntil, nil
+	}
+
+	// Overshoot a little so that frequently referenced blobs don't need extending on every backup.
+	newRetainUntil := now.Add(retention + objectLockExtensionSlack)
+	if err := extender.ExtendBlobRetention(ctx, key, newRetainUntil); err != nil {
+		retentionExtensionErrors.Inc()
+		return retainUntil, err
+	}
+	retentionExtensions.Inc()
+	zap.S().Debugw("blob_retention_extended", "key", key, "from", retainUntil, "to", newRetainUntil)
+	return newRetainUntil, nil
+}
+
 var (
 	skippedBytes = prometheus.NewCounter(prometheus.CounterOpts{
 		Namespace: "cassandrabackup",
@@ -151,6 +243,18 @@ var (
 		Name:      "upload_errors_total",
 		Help:      "Number of failed file uploads.",
 	})
+	retentionExtensions = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "cassandrabackup",
+		Subsystem: "bucket",
+		Name:      "retention_extensions_total",
+		Help:      "Number of existing blobs whose object lock was extended.",
+	})
+	retentionExtensionErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "cassandrabackup",
+		Subsystem: "bucket",
+		Name:      "retention_extension_errors_total",
+		Help:      "Number of failed object lock extensions.",
+	})
 )
 
 func init() {
@@ -159,4 +263,6 @@ func init() {
 	prometheus.MustRegister(uploadedBytes)
 	prometheus.MustRegister(uploadedFiles)
 	prometheus.MustRegister(uploadErrors)
+	prometheus.MustRegister(retentionExtensions)
+	prometheus.MustRegister(retentionExtensionErrors)
 }
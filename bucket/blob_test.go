@@ -0,0 +1,73 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bucket
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type lockingMemoryBackend struct {
+	*MemoryBackend
+	retention time.Duration
+	extended  map[string]time.Time
+}
+
+func (b *lockingMemoryBackend) BlobRetention() time.Duration {
+	return b.retention
+}
+
+func (b *lockingMemoryBackend) ExtendBlobRetention(ctx context.Context, key string, until time.Time) error {
+	b.extended[key] = until
+	return nil
+}
+
+func TestExtendRetention(t *testing.T) {
+	backend := &lockingMemoryBackend{
+		MemoryBackend: NewMemoryBackend(),
+		retention:     30 * 24 * time.Hour,
+		extended:      make(map[string]time.Time),
+	}
+	client := NewClient(backend, "")
+	now := time.Now()
+
+	longEnough := now.Add(backend.retention + time.Hour)
+	if retainUntil, err := client.extendRetention(context.Background(), "long", longEnough); err != nil {
+		t.Fatal(err)
+	} else if !retainUntil.Equal(longEnough) {
+		t.Fatalf("expected=%v actual=%v", longEnough, retainUntil)
+	}
+	if _, extended := backend.extended["long"]; extended {
+		t.Fatal("extended a blob that was already locked long enough")
+	}
+
+	for _, key := range []string{"expiring", "unlocked"} {
+		var previous time.Time
+		if key == "expiring" {
+			previous = now.Add(24 * time.Hour)
+		}
+		retainUntil, err := client.extendRetention(context.Background(), key, previous)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !retainUntil.After(now.Add(backend.retention)) {
+			t.Fatalf("key=%s not extended far enough: %v", key, retainUntil)
+		}
+		if !backend.extended[key].Equal(retainUntil) {
+			t.Fatalf("key=%s expected=%v actual=%v", key, retainUntil, backend.extended[key])
+		}
+	}
+}
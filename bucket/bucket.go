@@ -19,14 +19,9 @@ import (
 	"sync"
 	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
-	"github.com/aws/aws-sdk-go/service/s3/s3iface"
-	"github.com/aws/aws-sdk-go/service/s3/s3manager"
-	"github.com/aws/aws-sdk-go/service/s3/s3manager/s3manageriface"
-	"github.com/retailnext/cassandrabackup/bucket/safeuploader"
 	"github.com/retailnext/cassandrabackup/cache"
+	"github.com/retailnext/cassandrabackup/envelope"
 	"go.uber.org/zap"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
@@ -38,21 +33,42 @@ const listManifestsRetriesLimit = 3
 const retrySleepPerAttempt = time.Second
 
 type Client struct {
-	s3Svc       s3iface.S3API
-	uploader    *safeuploader.SafeUploader
-	downloader  s3manageriface.DownloaderAPI
+	backend     Backend
 	existsCache *ExistsCache
 
-	bucket               string
-	prefix               string
-	serverSideEncryption *string
+	prefix string
+
+	encryption        *envelope.Key
+	encryptionTempDir string
+	encryptionStrict  bool
 }
 
+const (
+	storageS3         = "s3"
+	storageFilesystem = "filesystem"
+)
+
 var (
-	bucketName             = kingpin.Flag("s3-bucket", "S3 bucket name.").Required().String()
-	bucketRegion           = kingpin.Flag("s3-region", "S3 bucket region.").Envar("AWS_REGION").Required().String()
+	storage = kingpin.Flag("storage", "Where to store backups.").Default(storageS3).Enum(storageS3, storageFilesystem)
+	fsPath  = kingpin.Flag("fs-path", "Directory to store backups in when using filesystem storage.").String()
+
+	bucketName             = kingpin.Flag("s3-bucket", "S3 bucket name.").String()
+	bucketRegion           = kingpin.Flag("s3-region", "S3 bucket region.").Envar("AWS_REGION").String()
 	bucketKeyPrefix        = kingpin.Flag("s3-key-prefix", "Set the prefix for files in the S3 bucket").Default("/").String()
 	bucketBlobStorageClass = kingpin.Flag("s3-storage-class", "Set the storage class for files in S3").Default(s3.StorageClassStandardIa).String()
+
+	bucketEndpoint             = kingpin.Flag("s3-endpoint", "Use a custom S3 endpoint URL (MinIO, Ceph RGW, etc).").String()
+	bucketForcePathStyle       = kingpin.Flag("s3-force-path-style", "Use path-style addressing for S3 requests.").Bool()
+	bucketCAFile               = kingpin.Flag("s3-ca-file", "Trust the CA certificates in this PEM file for S3 requests.").String()
+	bucketInsecureSkipVerify   = kingpin.Flag("s3-insecure-skip-verify", "Do not verify the S3 endpoint's TLS certificate.").Bool()
+	bucketServerSideEncryption = kingpin.Flag("s3-sse", "Server side encryption to request for uploads.").Default(s3.ServerSideEncryptionAes256).Enum(sseNone, s3.ServerSideEncryptionAes256, s3.ServerSideEncryptionAwsKms)
+	bucketSSEKMSKeyID          = kingpin.Flag("s3-sse-kms-key-id", "KMS key to encrypt uploads with when using --s3-sse=aws:kms.").String()
+	bucketObjectLockMode       = kingpin.Flag("s3-object-lock-mode", "Object lock mode to apply to uploads.").Enum(s3.ObjectLockModeGovernance, s3.ObjectLockModeCompliance)
+	bucketObjectLockRetention  = kingpin.Flag("s3-object-lock-retention", "How long uploads are locked for when using --s3-object-lock-mode.").Duration()
+
+	encryptionKeyFile = kingpin.Flag("encryption-key-file", "Encrypt blobs and manifests client side with the master key in this file.").String()
+	encryptionTempDir = kingpin.Flag("encryption-temp-dir", "Directory for encrypted copies of files while they are transferred.").String()
+	encryptionStrict  = kingpin.Flag("encryption-strict", "Refuse to read manifests and other documents that were stored unencrypted when using --encryption-key-file.").Bool()
 )
 
 var (
@@ -60,59 +76,59 @@ var (
 	once   sync.Once
 )
 
+// OpenShared returns Shared, creating it from the command line flags unless it has already been set.
 func OpenShared() *Client {
 	once.Do(func() {
-		Shared = newClient()
+		if Shared == nil {
+			Shared = newClient()
+		}
 	})
 	return Shared
 }
 
+// NewClient returns a Client without an exists cache, storing everything in backend under prefix.
+func NewClient(backend Backend, prefix string) *Client {
+	return &Client{
+		backend: backend,
+		prefix:  strings.Trim(prefix, "/"),
+	}
+}
+
+// EnableEncryption makes the client encrypt new blobs and documents with key, using tempDir for
+// encrypting and decrypting blobs.
+func (c *Client) EnableEncryption(key *envelope.Key, tempDir string) {
+	c.encryption = key
+	c.encryptionTempDir = tempDir
+}
+
 func newClient() *Client {
 	cache.OpenShared()
 
-	awsConf := aws.NewConfig().WithRegion(*bucketRegion)
-	awsSession, err := session.NewSession(awsConf)
-	if err != nil {
-		zap.S().Fatalw("aws_new_session_error", "err", err)
+	var backend Backend
+	switch *storage {
+	case storageFilesystem:
+		backend = newFilesystemBackend()
+	default:
+		backend = newS3Backend()
 	}
 
-	s3Svc := s3.New(awsSession)
-	c := &Client{
-		s3Svc: s3Svc,
-		uploader: &safeuploader.SafeUploader{
-			S3:                   s3Svc,
-			Bucket:               *bucketName,
-			ServerSideEncryption: aws.String(s3.ServerSideEncryptionAes256),
-			StorageClass:         bucketBlobStorageClass,
-		},
-		downloader: s3manager.NewDownloaderWithClient(s3Svc, func(d *s3manager.Downloader) {
-			d.PartSize = 64 * 1024 * 1024 // 64MB per part
-		}),
-		existsCache: &ExistsCache{
-			cache: cache.Shared.Cache("bucket_exists"),
-		},
-		bucket:               *bucketName,
-		prefix:               strings.Trim(*bucketKeyPrefix, "/"),
-		serverSideEncryption: aws.String(s3.ServerSideEncryptionAes256),
-	}
-	c.validateEncryptionConfiguration()
-	return c
-}
-
-func (c *Client) validateEncryptionConfiguration() {
-	input := &s3.GetBucketEncryptionInput{
-		Bucket: &c.bucket,
+	c := NewClient(backend, *bucketKeyPrefix)
+	existsCacheName := "bucket_exists"
+	if *encryptionKeyFile != "" {
+		key, err := envelope.LoadKeyFile(*encryptionKeyFile)
+		if err != nil {
+			zap.S().Fatalw("encryption_key_file_error", "err", err)
+		}
+		c.EnableEncryption(key, *encryptionTempDir)
+		c.encryptionStrict = *encryptionStrict
+		// Blobs that were uploaded before encryption was enabled must not be mistaken for encrypted ones.
+		existsCacheName = "bucket_exists_encrypted"
 	}
-	output, err := c.s3Svc.GetBucketEncryption(input)
-	if err != nil {
-		zap.S().Fatalw("failed_to_validate_bucket_encryption", "err", err)
+	c.existsCache = &ExistsCache{
+		cache: cache.Shared.Cache(existsCacheName),
 	}
-	for _, rule := range output.ServerSideEncryptionConfiguration.Rules {
-		if rule.ApplyServerSideEncryptionByDefault != nil {
-			if rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm != nil {
-				return
-			}
-		}
+	if extender, ok := backend.(retentionExtender); ok {
+		c.existsCache.retention = extender.BlobRetention()
 	}
-	zap.S().Fatalw("bucket_not_configured_with_sse_algorithm", "bucket", c.bucket)
+	return c
 }
@@ -0,0 +1,186 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bucket
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/retailnext/cassandrabackup/digest"
+	"github.com/retailnext/cassandrabackup/envelope"
+	"github.com/retailnext/cassandrabackup/paranoid"
+	"go.uber.org/zap"
+)
+
+var (
+	NoEncryptionKey   = errors.New("document is encrypted but no encryption key is configured")
+	PlaintextDocument = errors.New("document is not encrypted")
+)
+
+// encryptionContext ties an encrypted object to its key so that it can't be swapped for another one.
+// The prefix is left out so that backups can still be read after being moved under a different one.
+func (c *Client) encryptionContext(absoluteKey string) []byte {
+	return []byte(strings.TrimPrefix(absoluteKey, c.keyWithPrefix("")))
+}
+
+// putEncryptedBlob uploads an encrypted copy of file under the key for its plaintext digest.
+func (c *Client) putEncryptedBlob(ctx context.Context, key string, file paranoid.File) error {
+	return c.withTempFile(func(tempFile *os.File) error {
+		src, err := file.Open()
+		if err != nil {
+			return err
+		}
+		encryptErr := c.encryption.Encrypt(tempFile, src, c.encryptionContext(key))
+		checkErr := file.CheckFile(src)
+		if closeErr := src.Close(); closeErr != nil {
+			panic(closeErr)
+		}
+		if encryptErr != nil {
+			return encryptErr
+		}
+		if checkErr != nil {
+			return checkErr
+		}
+
+		encrypted, err := paranoid.NewFile(tempFile.Name())
+		if err != nil {
+			return err
+		}
+		encryptedDigests, err := digest.GetUncached(ctx, encrypted)
+		if err != nil {
+			return err
+		}
+		return c.backend.PutBlob(ctx, key, encrypted, encryptedDigests)
+	})
+}
+
+// downloadEncryptedBlob replaces the contents of file with the decrypted blob.
+// Blobs that were uploaded before encryption was enabled are passed through unchanged.
+func (c *Client) downloadEncryptedBlob(ctx context.Context, key string, file *os.File) error {
+	return c.withTempFile(func(tempFile *os.File) error {
+		if err := c.backend.DownloadBlob(ctx, key, tempFile); err != nil {
+			return err
+		}
+
+		header := make([]byte, envelope.HeaderLength)
+		if _, err := tempFile.ReadAt(header, 0); err != nil && err != io.EOF {
+			return err
+		}
+		if _, err := tempFile.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if err := file.Truncate(0); err != nil {
+			return err
+		}
+		if !envelope.IsEncrypted(header) {
+			_, err := io.Copy(file, tempFile)
+			return err
+		}
+		return c.encryption.Decrypt(file, tempFile, c.encryptionContext(key))
+	})
+}
+
+// openEncryptedBlob streams the decrypted blob. Decryption errors are returned from Read.
+func (c *Client) openEncryptedBlob(ctx context.Context, key string) (io.ReadCloser, error) {
+	body, err := c.backend.OpenBlob(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	reader := bufio.NewReader(body)
+	header, err := reader.Peek(envelope.HeaderLength)
+	if err != nil && err != io.EOF {
+		_ = body.Close()
+		return nil, err
+	}
+	if !envelope.IsEncrypted(header) {
+		return readCloser{Reader: reader, Closer: body}, nil
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(c.encryption.Decrypt(pw, reader, c.encryptionContext(key)))
+	}()
+	return readCloser{
+		Reader: pr,
+		Closer: closerFunc(func() error {
+			// Unblocks Decrypt if the caller stops reading early.
+			_ = pr.Close()
+			return body.Close()
+		}),
+	}, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error {
+	return f()
+}
+
+func (c *Client) withTempFile(op func(tempFile *os.File) error) error {
+	tempFile, err := ioutil.TempFile(c.encryptionTempDir, "cassandrabackup-*~")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		closeErr := tempFile.Close()
+		removeErr := os.Remove(tempFile.Name())
+		if closeErr != nil {
+			panic(closeErr)
+		}
+		if removeErr != nil && !os.IsNotExist(removeErr) {
+			panic(removeErr)
+		}
+	}()
+	return op(tempFile)
+}
+
+func (c *Client) sealDocument(absoluteKey string, document []byte) ([]byte, error) {
+	if c.encryption == nil {
+		return document, nil
+	}
+	return c.encryption.Seal(document, c.encryptionContext(absoluteKey))
+}
+
+// openDocument decrypts document. Documents written before encryption was enabled are passed through
+// unless encryptionStrict is set, since anyone who can write to the bucket could have planted them.
+func (c *Client) openDocument(absoluteKey string, document []byte) ([]byte, error) {
+	if !envelope.IsEncrypted(document) {
+		if c.encryption != nil {
+			if c.encryptionStrict {
+				zap.S().Errorw("plaintext_document", "key", absoluteKey)
+				return nil, PlaintextDocument
+			}
+			zap.S().Warnw("plaintext_document", "key", absoluteKey)
+		}
+		return document, nil
+	}
+	if c.encryption == nil {
+		return nil, NoEncryptionKey
+	}
+	return c.encryption.Open(document, c.encryptionContext(absoluteKey))
+}
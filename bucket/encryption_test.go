@@ -0,0 +1,127 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bucket
+
+import (
+	"context"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/retailnext/cassandrabackup/envelope"
+	"github.com/retailnext/cassandrabackup/manifests"
+	"github.com/retailnext/cassandrabackup/unixtime"
+)
+
+func newTestKey() *envelope.Key {
+	master := make([]byte, 32)
+	if _, err := rand.Read(master); err != nil {
+		panic(err)
+	}
+	key, err := envelope.NewKey(master)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+func TestEncryption(t *testing.T) {
+	key := newTestKey()
+
+	backend := NewMemoryBackend()
+	client := NewClient(backend, "")
+	client.encryption = key
+	testClient(t, client)
+
+	for name, obj := range backend.objects {
+		if !envelope.IsEncrypted(obj.value) {
+			t.Fatalf("stored in plaintext: %s", name)
+		}
+		if strings.HasPrefix(name, "files/") && int64(len(obj.value)) != envelope.CiphertextLength(1024) {
+			t.Fatalf("unexpected blob length: %d", len(obj.value))
+		}
+	}
+
+	client.encryption = nil
+	identity := manifests.NodeIdentity{
+		Cluster:  "test-cluster",
+		Hostname: "test-host",
+	}
+	keys, err := client.ListManifests(context.Background(), identity, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetManifests(context.Background(), identity, keys); err != NoEncryptionKey {
+		t.Fatalf("expected=%v actual=%v", NoEncryptionKey, err)
+	}
+}
+
+func TestEncryptedDocuments(t *testing.T) {
+	ctx := context.Background()
+	state := PruneState{
+		UnreferencedBlobs: map[string]unixtime.Seconds{"a": 1},
+	}
+
+	backend := NewMemoryBackend()
+	plaintext := NewClient(backend, "/old/")
+	if err := plaintext.PutPruneState(ctx, state); err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewClient(backend, "/old/")
+	client.encryption = newTestKey()
+	if actual, err := client.GetPruneState(ctx); err != nil {
+		t.Fatal(err)
+	} else if diff := deep.Equal(actual, state); diff != nil {
+		t.Fatal(diff)
+	}
+	client.encryptionStrict = true
+	if _, err := client.GetPruneState(ctx); err != PlaintextDocument {
+		t.Fatalf("expected=%v actual=%v", PlaintextDocument, err)
+	}
+
+	if err := client.PutPruneState(ctx, state); err != nil {
+		t.Fatal(err)
+	}
+	if actual, err := client.GetPruneState(ctx); err != nil {
+		t.Fatal(err)
+	} else if diff := deep.Equal(actual, state); diff != nil {
+		t.Fatal(diff)
+	}
+
+	// Moving the whole backup to another prefix keeps it readable...
+	moved := NewMemoryBackend()
+	for key, obj := range backend.objects {
+		moved.put(strings.Replace(key, "old/", "new/", 1), obj.value)
+	}
+	movedClient := NewClient(moved, "new")
+	movedClient.encryption = client.encryption
+	if _, err := movedClient.GetPruneState(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// ...but an encrypted document can't stand in for a different one.
+	document := backend.objects["old/prune/state"].value
+	backend.put(client.absoluteKeyForSchema("x"), document)
+	if _, err := client.GetSchema(ctx, "x"); err != envelope.WrongContext {
+		t.Fatalf("expected=%v actual=%v", envelope.WrongContext, err)
+	}
+
+	client.encryption = newTestKey()
+	if _, err := client.GetPruneState(ctx); err != envelope.WrongKey {
+		t.Fatalf("expected=%v actual=%v", envelope.WrongKey, err)
+	}
+}
@@ -28,9 +28,15 @@ const objectLockSafetyMargin = 12 * time.Hour
 
 type ExistsCache struct {
 	cache *cache.Cache
+	// retention is how long blobs are expected to stay locked for, if longer than objectLockSafetyMargin.
+	retention time.Duration
 }
 
+// Get is safe to call on a nil ExistsCache, and always misses.
 func (e *ExistsCache) Get(restore digest.ForRestore) bool {
+	if e == nil {
+		return false
+	}
 	var exists bool
 	key, err := restore.MarshalBinary()
 	if err != nil {
@@ -41,7 +47,11 @@ func (e *ExistsCache) Get(restore digest.ForRestore) bool {
 		if err := lockedUntil.UnmarshalBinary(value); err != nil {
 			return err
 		}
-		if time.Now().Add(objectLockSafetyMargin).Unix() < int64(lockedUntil) {
+		required := objectLockSafetyMargin
+		if e.retention > required {
+			required = e.retention
+		}
+		if time.Now().Add(required).Unix() < int64(lockedUntil) {
 			exists = true
 			return nil
 		} else {
@@ -60,6 +70,9 @@ func (e *ExistsCache) Get(restore digest.ForRestore) bool {
 }
 
 func (e *ExistsCache) Put(restore digest.ForRestore, lockedUntil time.Time) {
+	if e == nil {
+		return
+	}
 	key, err := restore.MarshalBinary()
 	if err != nil {
 		panic(err)
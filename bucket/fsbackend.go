@@ -0,0 +1,213 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bucket
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/retailnext/cassandrabackup/digest"
+	"github.com/retailnext/cassandrabackup/paranoid"
+	"github.com/retailnext/cassandrabackup/writefile"
+	"go.uber.org/zap"
+)
+
+// FilesystemBackend stores blobs and documents as files in a directory tree (such as an NFS mount),
+// using keys as relative paths.
+type FilesystemBackend struct {
+	target writefile.Config
+}
+
+func NewFilesystemBackend(directory string) *FilesystemBackend {
+	return &FilesystemBackend{
+		target: writefile.Config{
+			Directory: directory,
+		},
+	}
+}
+
+func newFilesystemBackend() *FilesystemBackend {
+	lgr := zap.S()
+	if *fsPath == "" {
+		lgr.Fatalw("fs_path_required")
+	}
+	directory, err := filepath.Abs(*fsPath)
+	if err != nil {
+		lgr.Fatalw("fs_path_error", "err", err)
+	}
+	info, err := os.Stat(directory)
+	if err != nil {
+		lgr.Fatalw("fs_path_error", "err", err)
+	}
+	if !info.IsDir() {
+		lgr.Fatalw("fs_path_not_directory", "path", directory)
+	}
+	return NewFilesystemBackend(directory)
+}
+
+func (b *FilesystemBackend) path(key string) string {
+	return filepath.Join(b.target.Directory, filepath.FromSlash(key))
+}
+
+func (b *FilesystemBackend) PutBlob(ctx context.Context, key string, file paranoid.File, digests digest.ForUpload) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := src.Close(); closeErr != nil {
+			panic(closeErr)
+		}
+	}()
+
+	return b.target.WriteFile(filepath.FromSlash(key), func(dst *os.File) error {
+		if _, err := io.Copy(dst, src); err != nil {
+			return err
+		}
+		if err := file.CheckFile(src); err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return dst.Sync()
+	})
+}
+
+func (b *FilesystemBackend) DownloadBlob(ctx context.Context, key string, file *os.File) error {
+	src, err := os.Open(b.path(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return NotFound
+		}
+		return err
+	}
+	defer func() {
+		if closeErr := src.Close(); closeErr != nil {
+			panic(closeErr)
+		}
+	}()
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, src); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+func (b *FilesystemBackend) OpenBlob(ctx context.Context, key string) (io.ReadCloser, error) {
+	file, err := os.Open(b.path(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, NotFound
+		}
+		return nil, err
+	}
+	return file, nil
+}
+
+func (b *FilesystemBackend) HeadBlob(ctx context.Context, key string) (BlobInfo, error) {
+	info, err := os.Stat(b.path(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return BlobInfo{}, NotFound
+		}
+		return BlobInfo{}, err
+	}
+	return BlobInfo{
+		ContentLength: info.Size(),
+		LastModified:  info.ModTime(),
+	}, nil
+}
+
+func (b *FilesystemBackend) PutDocument(ctx context.Context, key string, document []byte) error {
+	return b.target.WriteFile(filepath.FromSlash(key), func(file *os.File) error {
+		if _, err := file.Write(document); err != nil {
+			return err
+		}
+		return file.Sync()
+	})
+}
+
+func (b *FilesystemBackend) GetDocument(ctx context.Context, key string) ([]byte, error) {
+	document, err := ioutil.ReadFile(b.path(key))
+	if os.IsNotExist(err) {
+		return nil, NotFound
+	}
+	return document, err
+}
+
+func (b *FilesystemBackend) List(ctx context.Context, prefix, startAfter string, fn func(keys, prefixes []string) bool) error {
+	dirKey := ""
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		dirKey = prefix[:i+1]
+	}
+	namePrefix := prefix[len(dirKey):]
+
+	infos, err := ioutil.ReadDir(b.path(dirKey))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var keys, prefixes []string
+	for _, info := range infos {
+		name := info.Name()
+		if !strings.HasPrefix(name, namePrefix) || isTempFileName(name) {
+			continue
+		}
+		key := dirKey + name
+		if info.IsDir() {
+			key += "/"
+		}
+		if key <= startAfter {
+			continue
+		}
+		if info.IsDir() {
+			prefixes = append(prefixes, key)
+		} else {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	sort.Strings(prefixes)
+	fn(keys, prefixes)
+	return nil
+}
+
+func (b *FilesystemBackend) Delete(ctx context.Context, key string) error {
+	err := os.Remove(b.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func isTempFileName(name string) bool {
+	matched, _ := filepath.Match(writefile.DefaultTempPattern, name)
+	return matched
+}
@@ -18,12 +18,9 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
-	"time"
+	"io"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/mailru/easyjson"
-	"go.uber.org/zap"
 )
 
 func (c *Client) putDocument(ctx context.Context, absoluteKey string, v easyjson.Marshaler) error {
@@ -35,59 +32,45 @@ func (c *Client) putDocument(ctx context.Context, absoluteKey string, v easyjson
 	if err := gzipWriter.Close(); err != nil {
 		panic(err)
 	}
+	return c.putSealedDocument(ctx, absoluteKey, encodeBuffer.Bytes())
+}
 
-	putObjectInput := &s3.PutObjectInput{
-		Bucket:               &c.bucket,
-		Key:                  &absoluteKey,
-		ContentType:          aws.String("application/json"),
-		ContentEncoding:      aws.String("gzip"),
-		ServerSideEncryption: c.serverSideEncryption,
-		Body:                 bytes.NewReader(encodeBuffer.Bytes()),
-	}
-	attempts := 0
-	for {
-		_, err := c.s3Svc.PutObjectWithContext(ctx, putObjectInput)
-		if err != nil {
-			attempts++
-			if ctxErr := ctx.Err(); ctxErr != nil {
-				return ctxErr
-			}
-			if attempts > putJsonRetriesLimit {
-				return err
-			}
-			zap.S().Warnw("s3_put_object_error", "err", err, "attempts", attempts)
-			time.Sleep(time.Duration(attempts) * retrySleepPerAttempt)
-		} else {
-			return nil
-		}
+func (c *Client) putSealedDocument(ctx context.Context, absoluteKey string, document []byte) error {
+	document, err := c.sealDocument(absoluteKey, document)
+	if err != nil {
+		return err
 	}
+	return c.backend.PutDocument(ctx, absoluteKey, document)
 }
 
 func (c *Client) getDocument(ctx context.Context, absoluteKey string, v easyjson.Unmarshaler) error {
-	getObjectInput := &s3.GetObjectInput{
-		Bucket: &c.bucket,
-		Key:    &absoluteKey,
+	reader, err := c.openDocumentReader(ctx, absoluteKey)
+	if err != nil {
+		return err
+	}
+	return easyjson.UnmarshalFromReader(reader, v)
+}
+
+// openDocumentReader fetches a document, decrypting and decompressing it as needed.
+func (c *Client) openDocumentReader(ctx context.Context, absoluteKey string) (io.Reader, error) {
+	document, err := c.backend.GetDocument(ctx, absoluteKey)
+	if err != nil {
+		return nil, err
+	}
+	if document, err = c.openDocument(absoluteKey, document); err != nil {
+		return nil, err
 	}
-	attempts := 0
-	for {
-		getObjectOutput, err := c.s3Svc.GetObjectWithContext(ctx, getObjectInput)
+	var reader io.Reader = bytes.NewReader(document)
+	if isGzip(document) {
+		gzipReader, err := gzip.NewReader(reader)
 		if err != nil {
-			if IsNoSuchKey(err) {
-				return err
-			}
-			attempts++
-			if ctxErr := ctx.Err(); ctxErr != nil {
-				return ctxErr
-			}
-			if attempts > getJsonRetriesLimit {
-				return err
-			}
-			zap.S().Warnw("s3_get_object_error", "err", err, "attempts", attempts)
-			time.Sleep(time.Duration(attempts) * retrySleepPerAttempt)
-		} else {
-			err = easyjson.UnmarshalFromReader(getObjectOutput.Body, v)
-			_ = getObjectOutput.Body.Close()
-			return err
+			return nil, err
 		}
+		reader = gzipReader
 	}
+	return reader, nil
+}
+
+func isGzip(document []byte) bool {
+	return len(document) >= 2 && document[0] == 0x1f && document[1] == 0x8b
 }
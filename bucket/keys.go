@@ -19,7 +19,6 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/retailnext/cassandrabackup/digest"
 	"github.com/retailnext/cassandrabackup/manifests"
 	"github.com/retailnext/cassandrabackup/unixtime"
@@ -37,6 +36,63 @@ func (c *Client) absoluteKeyForBlob(digests digest.ForRestore) string {
 	return c.keyWithPrefix(fmt.Sprintf("files/blake2b/%s/%s/%s", encoded[0:1], encoded[1:2], encoded[2:]))
 }
 
+func (c *Client) absoluteKeyPrefixForBlobs() string {
+	return c.keyWithPrefix("files/blake2b/")
+}
+
+func (c *Client) decodeBlobKey(key string) (digest.ForRestore, bool) {
+	var result digest.ForRestore
+	prefix := c.absoluteKeyPrefixForBlobs()
+	if !strings.HasPrefix(key, prefix) {
+		return result, false
+	}
+	parts := strings.Split(key[len(prefix):], "/")
+	if len(parts) != 3 || len(parts[0]) != 1 || len(parts[1]) != 1 {
+		return result, false
+	}
+	raw, err := base64.URLEncoding.DecodeString(strings.Join(parts, ""))
+	if err != nil {
+		return result, false
+	}
+	if err := result.UnmarshalBinary(raw); err != nil {
+		return result, false
+	}
+	return result, true
+}
+
+func (c *Client) absoluteKeyForSchema(id string) string {
+	return c.absoluteKeyPrefixForSchemas() + id
+}
+
+func (c *Client) absoluteKeyPrefixForSchemas() string {
+	return c.keyWithPrefix("schemas/blake2b/")
+}
+
+func (c *Client) absoluteKeyForPruneState() string {
+	return c.keyWithPrefix("prune/state")
+}
+
+func (c *Client) decodeClusters(prefixes []string) ([]string, []string) {
+	result := make([]string, 0, len(prefixes))
+	var bonus []string
+	skip := len(c.absolteKeyPrefixForClusters())
+	for _, raw := range prefixes {
+		trimmed := raw[skip:]
+		parts := strings.Split(trimmed, "/")
+		if len(parts) != 2 {
+			bonus = append(bonus, raw)
+			continue
+		}
+		cluster, err := base64.URLEncoding.DecodeString(parts[0])
+		if err != nil {
+			bonus = append(bonus, raw)
+			continue
+		}
+		result = append(result, string(cluster))
+	}
+	return result, bonus
+}
+
 func (c *Client) absolteKeyPrefixForClusters() string {
 	return c.keyWithPrefix("manifests/")
 }
@@ -50,12 +106,11 @@ func (c *Client) absoluteKeyPrefixForClusterHosts(cluster string) string {
 	return fmt.Sprintf("%s%s/", clustersPrefix, urlCluster)
 }
 
-func (c *Client) decodeClusterHosts(prefixes []*s3.CommonPrefix) ([]manifests.NodeIdentity, []string) {
+func (c *Client) decodeClusterHosts(prefixes []string) ([]manifests.NodeIdentity, []string) {
 	result := make([]manifests.NodeIdentity, 0, len(prefixes))
 	var bonus []string
 	skip := len(c.absolteKeyPrefixForClusters())
-	for _, obj := range prefixes {
-		raw := *obj.Prefix
+	for _, raw := range prefixes {
 		trimmed := raw[skip:]
 		parts := strings.Split(trimmed, "/")
 		if len(parts) != 3 {
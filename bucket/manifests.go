@@ -16,25 +16,31 @@ package bucket
 
 import (
 	"context"
-	"path/filepath"
+	"errors"
+	"path"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/retailnext/cassandrabackup/manifests"
 	"github.com/retailnext/cassandrabackup/unixtime"
 	"go.uber.org/zap"
 )
 
+var UnparseableManifestKey = errors.New("found a manifest key that could not be parsed")
+
+// ListManifests skips keys that can't be parsed as manifest keys.
 func (c *Client) ListManifests(ctx context.Context, identity manifests.NodeIdentity, startAfter, notAfter unixtime.Seconds) (manifests.ManifestKeys, error) {
+	return c.listManifests(ctx, identity, startAfter, notAfter, false)
+}
+
+// ListManifestsStrict is like ListManifests, but returns UnparseableManifestKey instead of skipping keys.
+// Anything deciding what is no longer referenced must not overlook manifests.
+func (c *Client) ListManifestsStrict(ctx context.Context, identity manifests.NodeIdentity, startAfter, notAfter unixtime.Seconds) (manifests.ManifestKeys, error) {
+	return c.listManifests(ctx, identity, startAfter, notAfter, true)
+}
+
+func (c *Client) listManifests(ctx context.Context, identity manifests.NodeIdentity, startAfter, notAfter unixtime.Seconds, strict bool) (manifests.ManifestKeys, error) {
 	lgr := zap.S()
 	prefixKey := c.absoluteKeyPrefixForManifests(identity)
 	startAfterKey := c.absoluteKeyForManifestTimeRange(identity, startAfter)
-	input := &s3.ListObjectsV2Input{
-		Bucket:     &c.bucket,
-		Delimiter:  aws.String("/"),
-		Prefix:     &prefixKey,
-		StartAfter: &startAfterKey,
-	}
 
 	notAfterKey := ""
 	if notAfter > 0 {
@@ -43,20 +49,21 @@ func (c *Client) ListManifests(ctx context.Context, identity manifests.NodeIdent
 	attempts := 0
 	for {
 		var keys manifests.ManifestKeys
-		err := c.s3Svc.ListObjectsV2PagesWithContext(ctx, input, func(output *s3.ListObjectsV2Output, b bool) bool {
+		var badKey bool
+		err := c.backend.List(ctx, prefixKey, startAfterKey, func(objectKeys, prefixes []string) bool {
 			var done bool
-			for _, commonPrefix := range output.CommonPrefixes {
-				lgr.Debugw("list_manifests_saw_common_prefix", "prefix", commonPrefix.Prefix)
+			for _, commonPrefix := range prefixes {
+				lgr.Debugw("list_manifests_saw_common_prefix", "prefix", commonPrefix)
 			}
-			for _, obj := range output.Contents {
-				key := *obj.Key
+			for _, key := range objectKeys {
 				if notAfterKey != "" && key > notAfterKey {
 					done = true
 				} else {
-					name := filepath.Base(key)
+					name := path.Base(key)
 					var manifestKey manifests.ManifestKey
 					if err := manifestKey.PopulateFromFileName(name); err != nil {
 						lgr.Warnw("list_manifests_ignoring_bad_filename", "name", name, "err", err)
+						badKey = true
 					} else {
 						keys = append(keys, manifestKey)
 					}
@@ -72,7 +79,9 @@ func (c *Client) ListManifests(ctx context.Context, identity manifests.NodeIdent
 			if IsNoSuchKey(err) || attempts > listManifestsRetriesLimit {
 				return nil, err
 			}
-			lgr.Errorw("list_manifests_s3_error", "err", err, "attempts", attempts)
+			lgr.Errorw("list_manifests_error", "err", err, "attempts", attempts)
+		} else if badKey && strict {
+			return nil, UnparseableManifestKey
 		} else {
 			return keys, nil
 		}
@@ -87,6 +96,11 @@ func (c *Client) PutManifest(ctx context.Context, identity manifests.NodeIdentit
 	return c.putDocument(ctx, absoluteKey, manifest)
 }
 
+func (c *Client) DeleteManifest(ctx context.Context, identity manifests.NodeIdentity, manifestKey manifests.ManifestKey) error {
+	absoluteKey := c.absoluteKeyForManifest(identity, manifestKey)
+	return c.backend.Delete(ctx, absoluteKey)
+}
+
 func (c *Client) GetManifests(ctx context.Context, identity manifests.NodeIdentity, keys manifests.ManifestKeys) ([]manifests.Manifest, error) {
 	var results []manifests.Manifest
 	for _, manifestKey := range keys {
@@ -0,0 +1,336 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bucket
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/retailnext/cassandrabackup/digest"
+	"github.com/retailnext/cassandrabackup/paranoid"
+)
+
+var VersionLocked = errors.New("object version is locked")
+
+// MemoryBackend keeps everything in memory. It is intended for tests.
+type MemoryBackend struct {
+	lock    sync.Mutex
+	objects map[string]memoryObject
+
+	// versions is every version of each key, newest first, when simulating a versioned bucket.
+	versions    map[string][]memoryObject
+	retention   time.Duration
+	lastVersion int
+}
+
+type memoryObject struct {
+	value        []byte
+	lastModified time.Time
+
+	versionID    string
+	deleteMarker bool
+	retainUntil  time.Time
+}
+
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		objects: make(map[string]memoryObject),
+	}
+}
+
+// NewVersionedMemoryBackend simulates a bucket with versioning, where deleting or overwriting an
+// object keeps the previous version. If retention is positive, each version is locked for that long.
+func NewVersionedMemoryBackend(retention time.Duration) *MemoryBackend {
+	return &MemoryBackend{
+		objects:   make(map[string]memoryObject),
+		versions:  make(map[string][]memoryObject),
+		retention: retention,
+	}
+}
+
+func (b *MemoryBackend) put(key string, value []byte) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	obj := memoryObject{
+		value:        value,
+		lastModified: time.Now(),
+	}
+	if b.versions != nil {
+		obj.versionID = b.nextVersionID()
+		if b.retention > 0 {
+			obj.retainUntil = obj.lastModified.Add(b.retention)
+		}
+		b.versions[key] = append([]memoryObject{obj}, b.versions[key]...)
+	}
+	b.objects[key] = obj
+}
+
+func (b *MemoryBackend) nextVersionID() string {
+	b.lastVersion++
+	return strconv.Itoa(b.lastVersion)
+}
+
+func (b *MemoryBackend) get(key string) (memoryObject, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	obj, ok := b.objects[key]
+	return obj, ok
+}
+
+func (b *MemoryBackend) PutBlob(ctx context.Context, key string, file paranoid.File, digests digest.ForUpload) error {
+	osFile, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := osFile.Close(); closeErr != nil {
+			panic(closeErr)
+		}
+	}()
+	value, err := ioutil.ReadAll(osFile)
+	if err != nil {
+		return err
+	}
+	if err := file.CheckFile(osFile); err != nil {
+		return err
+	}
+	b.put(key, value)
+	return nil
+}
+
+func (b *MemoryBackend) DownloadBlob(ctx context.Context, key string, file *os.File) error {
+	obj, ok := b.get(key)
+	if !ok {
+		return NotFound
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := file.Truncate(0); err != nil {
+		return err
+	}
+	_, err := file.Write(obj.value)
+	return err
+}
+
+func (b *MemoryBackend) OpenBlob(ctx context.Context, key string) (io.ReadCloser, error) {
+	obj, ok := b.get(key)
+	if !ok {
+		return nil, NotFound
+	}
+	return ioutil.NopCloser(bytes.NewReader(obj.value)), nil
+}
+
+func (b *MemoryBackend) HeadBlob(ctx context.Context, key string) (BlobInfo, error) {
+	obj, ok := b.get(key)
+	if !ok {
+		return BlobInfo{}, NotFound
+	}
+	return BlobInfo{
+		ContentLength: int64(len(obj.value)),
+		LastModified:  obj.lastModified,
+		RetainUntil:   obj.retainUntil,
+	}, nil
+}
+
+func (b *MemoryBackend) BlobRetention() time.Duration {
+	return b.retention
+}
+
+func (b *MemoryBackend) ExtendBlobRetention(ctx context.Context, key string, until time.Time) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	obj, ok := b.objects[key]
+	if !ok {
+		return NotFound
+	}
+	if until.After(obj.retainUntil) {
+		obj.retainUntil = until
+		b.objects[key] = obj
+		if b.versions != nil {
+			b.versions[key][0] = obj
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBackend) PutDocument(ctx context.Context, key string, document []byte) error {
+	value := make([]byte, len(document))
+	copy(value, document)
+	b.put(key, value)
+	return nil
+}
+
+func (b *MemoryBackend) GetDocument(ctx context.Context, key string) ([]byte, error) {
+	obj, ok := b.get(key)
+	if !ok {
+		return nil, NotFound
+	}
+	return obj.value, nil
+}
+
+func (b *MemoryBackend) List(ctx context.Context, prefix, startAfter string, fn func(keys, prefixes []string) bool) error {
+	b.lock.Lock()
+	var keys, prefixes []string
+	seenPrefixes := make(map[string]struct{})
+	for key := range b.objects {
+		if !strings.HasPrefix(key, prefix) || key <= startAfter {
+			continue
+		}
+		if i := strings.Index(key[len(prefix):], "/"); i >= 0 {
+			commonPrefix := key[:len(prefix)+i+1]
+			if _, seen := seenPrefixes[commonPrefix]; !seen {
+				seenPrefixes[commonPrefix] = struct{}{}
+				prefixes = append(prefixes, commonPrefix)
+			}
+			continue
+		}
+		keys = append(keys, key)
+	}
+	b.lock.Unlock()
+
+	sort.Strings(keys)
+	sort.Strings(prefixes)
+	fn(keys, prefixes)
+	return nil
+}
+
+func (b *MemoryBackend) Delete(ctx context.Context, key string) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	delete(b.objects, key)
+	if b.versions != nil {
+		// Like S3, deleting without a version ID only adds a delete marker.
+		marker := memoryObject{
+			lastModified: time.Now(),
+			versionID:    b.nextVersionID(),
+			deleteMarker: true,
+		}
+		b.versions[key] = append([]memoryObject{marker}, b.versions[key]...)
+	}
+	return nil
+}
+
+func (b *MemoryBackend) ListVersions(ctx context.Context, prefix string, fn func(versions []ObjectVersion) bool) error {
+	b.lock.Lock()
+	var versions []ObjectVersion
+	if b.versions == nil {
+		// Without versioning, every object is the latest version of its key.
+		for key, obj := range b.objects {
+			if strings.HasPrefix(key, prefix) {
+				versions = append(versions, ObjectVersion{
+					Key:          key,
+					VersionID:    "null",
+					IsLatest:     true,
+					Size:         int64(len(obj.value)),
+					LastModified: obj.lastModified,
+				})
+			}
+		}
+	} else {
+		for key, history := range b.versions {
+			if !strings.HasPrefix(key, prefix) {
+				continue
+			}
+			for i, obj := range history {
+				versions = append(versions, ObjectVersion{
+					Key:          key,
+					VersionID:    obj.versionID,
+					IsLatest:     i == 0,
+					DeleteMarker: obj.deleteMarker,
+					Size:         int64(len(obj.value)),
+					LastModified: obj.lastModified,
+				})
+			}
+		}
+	}
+	b.lock.Unlock()
+
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].Key < versions[j].Key
+	})
+	fn(versions)
+	return nil
+}
+
+// findVersion returns the index of a version in the key's history, or -1. The caller must hold the lock.
+func (b *MemoryBackend) findVersion(key, versionID string) int {
+	for i, obj := range b.versions[key] {
+		if obj.versionID == versionID {
+			return i
+		}
+	}
+	return -1
+}
+
+func (b *MemoryBackend) VersionRetainUntil(ctx context.Context, key, versionID string) (time.Time, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.versions == nil {
+		obj, ok := b.objects[key]
+		if !ok || versionID != "null" {
+			return time.Time{}, NotFound
+		}
+		return obj.retainUntil, nil
+	}
+	i := b.findVersion(key, versionID)
+	if i < 0 {
+		return time.Time{}, NotFound
+	}
+	return b.versions[key][i].retainUntil, nil
+}
+
+func (b *MemoryBackend) DeleteVersion(ctx context.Context, key, versionID string) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.versions == nil {
+		if versionID == "null" {
+			delete(b.objects, key)
+		}
+		return nil
+	}
+	i := b.findVersion(key, versionID)
+	if i < 0 {
+		return nil
+	}
+	history := b.versions[key]
+	if history[i].retainUntil.After(time.Now()) {
+		return VersionLocked
+	}
+	history = append(history[:i], history[i+1:]...)
+	if len(history) == 0 {
+		delete(b.versions, key)
+	} else {
+		b.versions[key] = history
+	}
+	if i == 0 {
+		// The previous version, if any, becomes current again.
+		if len(history) == 0 || history[0].deleteMarker {
+			delete(b.objects, key)
+		} else {
+			b.objects[key] = history[0]
+		}
+	}
+	return nil
+}
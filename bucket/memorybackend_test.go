@@ -0,0 +1,198 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bucket
+
+import (
+	"context"
+	"crypto/rand"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/retailnext/cassandrabackup/digest"
+	"github.com/retailnext/cassandrabackup/manifests"
+	"github.com/retailnext/cassandrabackup/paranoid"
+)
+
+func TestMemoryBackend(t *testing.T) {
+	testClient(t, NewClient(NewMemoryBackend(), "/some/prefix/"))
+}
+
+func testClient(t *testing.T, client *Client) {
+	ctx := context.Background()
+
+	tempFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		panic(err)
+	}
+	tempFileName := tempFile.Name()
+	defer func() {
+		closeErr := tempFile.Close()
+		cleanupErr := os.Remove(tempFileName)
+		if closeErr != nil {
+			panic(closeErr)
+		}
+		if cleanupErr != nil {
+			panic(cleanupErr)
+		}
+	}()
+	buf := make([]byte, 1024)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	if _, err := tempFile.Write(buf); err != nil {
+		panic(err)
+	}
+
+	parFile, err := paranoid.NewFile(tempFileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dgst, err := digest.GetUncached(ctx, parFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.PutBlob(ctx, parFile, dgst); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.PutBlob(ctx, parFile, dgst); err != UploadSkipped {
+		t.Fatalf("expected UploadSkipped, got %v", err)
+	}
+	if err := client.DownloadBlob(ctx, dgst.ForRestore(), tempFile); err != nil {
+		t.Fatal(err)
+	}
+	blobReader, err := client.OpenBlob(ctx, dgst.ForRestore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dgst.ForRestore().VerifyStream(ctx, blobReader); err != nil {
+		t.Fatal(err)
+	}
+	if err := blobReader.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	identity := manifests.NodeIdentity{
+		Cluster:  "test-cluster",
+		Hostname: "test-host",
+	}
+	m1 := manifests.Manifest{
+		Time:         1000,
+		ManifestType: manifests.ManifestTypeSnapshot,
+		HostID:       "foobar",
+		Tokens:       []string{"-1", "1"},
+		DataFiles: map[string]digest.ForRestore{
+			"ks/table-1234/md-1-big-Data.db": dgst.ForRestore(),
+		},
+	}
+	m2 := m1
+	m2.Time = 2000
+	m2.ManifestType = manifests.ManifestTypeIncremental
+	for _, m := range []manifests.Manifest{m1, m2} {
+		if err := client.PutManifest(ctx, identity, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	hosts, err := client.ListHostNames(ctx, identity.Cluster)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(hosts, []manifests.NodeIdentity{identity}); diff != nil {
+		t.Fatal(diff)
+	}
+
+	keys, err := client.ListManifests(ctx, identity, 0, 1500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(keys, manifests.ManifestKeys{m1.Key()}); diff != nil {
+		t.Fatal(diff)
+	}
+
+	keys, err = client.ListManifests(ctx, identity, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := client.GetManifests(ctx, identity, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deep.CompareUnexportedFields = true
+	if diff := deep.Equal(got, []manifests.Manifest{m1, m2}); diff != nil {
+		t.Fatal(diff)
+	}
+
+	schema := []byte("CREATE KEYSPACE ks WITH replication = {'class': 'SimpleStrategy', 'replication_factor': '1'} AND durable_writes = true;\n")
+	schemaID, err := client.PutSchema(ctx, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotSchema, err := client.GetSchema(ctx, schemaID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(gotSchema, schema); diff != nil {
+		t.Fatal(diff)
+	}
+	var schemaIDs []string
+	if err := client.ListSchemas(ctx, func(id string) bool {
+		schemaIDs = append(schemaIDs, id)
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(schemaIDs, []string{schemaID}); diff != nil {
+		t.Fatal(diff)
+	}
+
+	clusters, err := client.ListClusters(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(clusters, []string{identity.Cluster}); diff != nil {
+		t.Fatal(diff)
+	}
+
+	var blobs []digest.ForRestore
+	if err := client.ListBlobs(ctx, func(digests digest.ForRestore) bool {
+		blobs = append(blobs, digests)
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(blobs, []digest.ForRestore{dgst.ForRestore()}); diff != nil {
+		t.Fatal(diff)
+	}
+
+	if err := client.DeleteBlob(ctx, dgst.ForRestore()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.StatBlob(ctx, dgst.ForRestore()); err != NotFound {
+		t.Fatalf("expected=%v actual=%v", NotFound, err)
+	}
+	if err := client.DeleteManifest(ctx, identity, m1.Key()); err != nil {
+		t.Fatal(err)
+	}
+	keys, err = client.ListManifests(ctx, identity, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(keys, manifests.ManifestKeys{m2.Key()}); diff != nil {
+		t.Fatal(diff)
+	}
+}
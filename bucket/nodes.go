@@ -17,33 +17,40 @@ package bucket
 import (
 	"context"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/retailnext/cassandrabackup/manifests"
 	"go.uber.org/zap"
 )
 
+func (c *Client) ListClusters(ctx context.Context) ([]string, error) {
+	lgr := zap.S()
+	prefix := c.absolteKeyPrefixForClusters()
+	var result []string
+	err := c.backend.List(ctx, prefix, "", func(keys, prefixes []string) bool {
+		clusters, bonus := c.decodeClusters(prefixes)
+		if len(bonus) > 0 {
+			lgr.Warnw("unexpected_objects_in_bucket", "keys", bonus)
+		}
+		result = append(result, clusters...)
+		if len(keys) > 0 {
+			lgr.Warnw("unexpected_objects_in_bucket", "keys", keys)
+		}
+		return true
+	})
+	return result, err
+}
+
 func (c *Client) ListHostNames(ctx context.Context, cluster string) ([]manifests.NodeIdentity, error) {
 	lgr := zap.S()
 	prefix := c.absoluteKeyPrefixForClusterHosts(cluster)
-	input := &s3.ListObjectsV2Input{
-		Bucket:    &c.bucket,
-		Delimiter: aws.String("/"),
-		Prefix:    &prefix,
-	}
 	var result []manifests.NodeIdentity
-	err := c.s3Svc.ListObjectsV2PagesWithContext(ctx, input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
-		nodes, bonus := c.decodeClusterHosts(page.CommonPrefixes)
+	err := c.backend.List(ctx, prefix, "", func(keys, prefixes []string) bool {
+		nodes, bonus := c.decodeClusterHosts(prefixes)
 		if len(bonus) > 0 {
 			lgr.Warnw("unexpected_objects_in_bucket", "keys", bonus)
 		}
 		result = append(result, nodes...)
-		if len(page.Contents) > 0 {
-			unexpected := make([]string, 0, len(page.Contents))
-			for _, o := range page.Contents {
-				unexpected = append(unexpected, *o.Key)
-			}
-			lgr.Warnw("unexpected_objects_in_bucket", "keys", unexpected)
+		if len(keys) > 0 {
+			lgr.Warnw("unexpected_objects_in_bucket", "keys", keys)
 		}
 		return true
 	})
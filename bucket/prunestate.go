@@ -0,0 +1,38 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bucket
+
+import (
+	"context"
+
+	"github.com/retailnext/cassandrabackup/unixtime"
+)
+
+//easyjson:json
+type PruneState struct {
+	// UnreferencedBlobs records when prune first found each unreferenced blob, keyed by URL safe digest.
+	UnreferencedBlobs map[string]unixtime.Seconds `json:"unreferenced_blobs"`
+}
+
+// GetPruneState returns NotFound if prune hasn't saved any state yet.
+func (c *Client) GetPruneState(ctx context.Context) (PruneState, error) {
+	var state PruneState
+	err := c.getDocument(ctx, c.absoluteKeyForPruneState(), &state)
+	return state, err
+}
+
+func (c *Client) PutPruneState(ctx context.Context, state PruneState) error {
+	return c.putDocument(ctx, c.absoluteKeyForPruneState(), state)
+}
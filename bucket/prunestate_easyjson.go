@@ -0,0 +1,120 @@
+// Code generated by easyjson for marshaling/unmarshaling. DO NOT EDIT.
+
+package bucket
+
+import (
+	json "encoding/json"
+	easyjson "github.com/mailru/easyjson"
+	jlexer "github.com/mailru/easyjson/jlexer"
+	jwriter "github.com/mailru/easyjson/jwriter"
+	unixtime "github.com/retailnext/cassandrabackup/unixtime"
+)
+
+// suppress unused package warning
+var (
+	_ *json.RawMessage
+	_ *jlexer.Lexer
+	_ *jwriter.Writer
+	_ easyjson.Marshaler
+)
+
+func easyjson81ca0297DecodeGithubComRetailnextCassandrabackupBucket(in *jlexer.Lexer, out *PruneState) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "unreferenced_blobs":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				in.Delim('{')
+				if !in.IsDelim('}') {
+					out.UnreferencedBlobs = make(map[string]unixtime.Seconds)
+				} else {
+					out.UnreferencedBlobs = nil
+				}
+				for !in.IsDelim('}') {
+					key := string(in.String())
+					in.WantColon()
+					var v1 unixtime.Seconds
+					(v1).UnmarshalEasyJSON(in)
+					(out.UnreferencedBlobs)[key] = v1
+					in.WantComma()
+				}
+				in.Delim('}')
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson81ca0297EncodeGithubComRetailnextCassandrabackupBucket(out *jwriter.Writer, in PruneState) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"unreferenced_blobs\":"
+		out.RawString(prefix[1:])
+		if in.UnreferencedBlobs == nil && (out.Flags&jwriter.NilMapAsEmpty) == 0 {
+			out.RawString(`null`)
+		} else {
+			out.RawByte('{')
+			v2First := true
+			for v2Name, v2Value := range in.UnreferencedBlobs {
+				if v2First {
+					v2First = false
+				} else {
+					out.RawByte(',')
+				}
+				out.String(string(v2Name))
+				out.RawByte(':')
+				(v2Value).MarshalEasyJSON(out)
+			}
+			out.RawByte('}')
+		}
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v PruneState) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson81ca0297EncodeGithubComRetailnextCassandrabackupBucket(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v PruneState) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson81ca0297EncodeGithubComRetailnextCassandrabackupBucket(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *PruneState) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson81ca0297DecodeGithubComRetailnextCassandrabackupBucket(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *PruneState) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson81ca0297DecodeGithubComRetailnextCassandrabackupBucket(l, v)
+}
@@ -0,0 +1,541 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bucket
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager/s3manageriface"
+	"github.com/retailnext/cassandrabackup/bucket/safeuploader"
+	"github.com/retailnext/cassandrabackup/digest"
+	"github.com/retailnext/cassandrabackup/paranoid"
+	"go.uber.org/zap"
+)
+
+type s3Backend struct {
+	s3Svc      s3iface.S3API
+	uploader   *safeuploader.SafeUploader
+	downloader s3manageriface.DownloaderAPI
+
+	bucket               string
+	serverSideEncryption *string
+	sseKMSKeyID          *string
+	// customEndpoint is set for S3 compatible implementations other than AWS.
+	customEndpoint bool
+
+	objectLockMode      *string
+	objectLockRetention time.Duration
+}
+
+const sseNone = "none"
+
+var (
+	BucketEncryptionNotConfigured = errors.New("bucket has no default encryption configured")
+	BucketEncryptionNotSSE        = errors.New("bucket default encryption has no server side encryption algorithm")
+)
+
+// Error codes returned instead of an encryption configuration by buckets without one, or by S3 implementations
+// that do not support bucket encryption configurations at all.
+const (
+	errCodeNoEncryptionConfiguration = "ServerSideEncryptionConfigurationNotFoundError"
+	errCodeNotImplemented            = "NotImplemented"
+)
+
+func newS3Backend() *s3Backend {
+	lgr := zap.S()
+	if *bucketName == "" {
+		lgr.Fatalw("s3_bucket_required")
+	}
+
+	awsConf := aws.NewConfig().WithRegion(*bucketRegion)
+	if *bucketEndpoint != "" {
+		awsConf = awsConf.WithEndpoint(*bucketEndpoint)
+		if *bucketRegion == "" {
+			// Most S3 compatible implementations ignore the region, but requests must still be signed for one.
+			awsConf = awsConf.WithRegion("us-east-1")
+		}
+	} else if *bucketRegion == "" {
+		lgr.Fatalw("s3_region_required")
+	}
+	if *bucketForcePathStyle {
+		awsConf = awsConf.WithS3ForcePathStyle(true)
+	}
+	if *bucketCAFile != "" || *bucketInsecureSkipVerify {
+		awsConf = awsConf.WithHTTPClient(newS3HTTPClient())
+	}
+	awsSession, err := session.NewSession(awsConf)
+	if err != nil {
+		lgr.Fatalw("aws_new_session_error", "err", err)
+	}
+
+	var serverSideEncryption, sseKMSKeyID *string
+	if *bucketServerSideEncryption != sseNone {
+		serverSideEncryption = bucketServerSideEncryption
+	}
+	if *bucketSSEKMSKeyID != "" {
+		if *bucketServerSideEncryption != s3.ServerSideEncryptionAwsKms {
+			lgr.Fatalw("s3_sse_kms_key_id_requires_aws_kms", "sse", *bucketServerSideEncryption)
+		}
+		sseKMSKeyID = bucketSSEKMSKeyID
+	}
+
+	var objectLockMode *string
+	if *bucketObjectLockMode != "" {
+		if *bucketObjectLockRetention <= 0 {
+			lgr.Fatalw("s3_object_lock_retention_required", "mode", *bucketObjectLockMode)
+		}
+		objectLockMode = bucketObjectLockMode
+	} else if *bucketObjectLockRetention != 0 {
+		lgr.Fatalw("s3_object_lock_mode_required", "retention", *bucketObjectLockRetention)
+	}
+
+	s3Svc := s3.New(awsSession)
+	b := &s3Backend{
+		s3Svc: s3Svc,
+		uploader: &safeuploader.SafeUploader{
+			S3:                   s3Svc,
+			Bucket:               *bucketName,
+			ServerSideEncryption: serverSideEncryption,
+			SSEKMSKeyID:          sseKMSKeyID,
+			StorageClass:         bucketBlobStorageClass,
+			ObjectLockMode:       objectLockMode,
+			ObjectLockRetention:  *bucketObjectLockRetention,
+		},
+		downloader: s3manager.NewDownloaderWithClient(s3Svc, func(d *s3manager.Downloader) {
+			d.PartSize = 64 * 1024 * 1024 // 64MB per part
+		}),
+		bucket:               *bucketName,
+		serverSideEncryption: serverSideEncryption,
+		sseKMSKeyID:          sseKMSKeyID,
+		customEndpoint:       *bucketEndpoint != "",
+		objectLockMode:       objectLockMode,
+		objectLockRetention:  *bucketObjectLockRetention,
+	}
+	if err := b.validateEncryptionConfiguration(); err != nil {
+		lgr.Fatalw("failed_to_validate_bucket_encryption", "bucket", b.bucket, "err", err)
+	}
+	b.validateObjectLockConfiguration()
+	return b
+}
+
+func newS3HTTPClient() *http.Client {
+	lgr := zap.S()
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: *bucketInsecureSkipVerify,
+	}
+	if *bucketCAFile != "" {
+		pem, err := ioutil.ReadFile(*bucketCAFile)
+		if err != nil {
+			lgr.Fatalw("s3_ca_file_read_error", "err", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			lgr.Fatalw("s3_ca_file_invalid", "file", *bucketCAFile)
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{
+		Transport: transport,
+	}
+}
+
+func (b *s3Backend) validateEncryptionConfiguration() error {
+	lgr := zap.S()
+	if b.serverSideEncryption == nil {
+		lgr.Infow("not_validating_bucket_encryption", "reason", "sse_disabled")
+		return nil
+	}
+
+	input := &s3.GetBucketEncryptionInput{
+		Bucket: &b.bucket,
+	}
+	output, err := b.s3Svc.GetBucketEncryption(input)
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok {
+			switch awsErr.Code() {
+			case errCodeNoEncryptionConfiguration, errCodeNotImplemented:
+				if b.sseKMSKeyID != nil || !b.customEndpoint {
+					// Only the bucket can make sure that everything in it is encrypted (with the key), and AWS supports it.
+					return BucketEncryptionNotConfigured
+				}
+				// Many S3 compatible implementations have no default encryption, and every upload
+				// requests server side encryption explicitly, so this is tolerable there.
+				lgr.Warnw("bucket_encryption_not_configured", "bucket", b.bucket, "code", awsErr.Code())
+				return nil
+			}
+		}
+		return err
+	}
+	for _, rule := range output.ServerSideEncryptionConfiguration.Rules {
+		if byDefault := rule.ApplyServerSideEncryptionByDefault; byDefault != nil {
+			if byDefault.SSEAlgorithm != nil {
+				if b.sseKMSKeyID == nil {
+					return nil
+				}
+				if *byDefault.SSEAlgorithm == s3.ServerSideEncryptionAwsKms && byDefault.KMSMasterKeyID != nil {
+					if sameKMSKey(*byDefault.KMSMasterKeyID, *b.sseKMSKeyID) {
+						return nil
+					}
+				}
+				return fmt.Errorf("bucket default encryption %s with key %s does not match key %s",
+					aws.StringValue(byDefault.SSEAlgorithm), aws.StringValue(byDefault.KMSMasterKeyID), *b.sseKMSKeyID)
+			}
+		}
+	}
+	return BucketEncryptionNotSSE
+}
+
+func (b *s3Backend) validateObjectLockConfiguration() {
+	lgr := zap.S()
+	if b.objectLockMode == nil {
+		return
+	}
+
+	input := &s3.GetObjectLockConfigurationInput{
+		Bucket: &b.bucket,
+	}
+	output, err := b.s3Svc.GetObjectLockConfiguration(input)
+	if err != nil {
+		lgr.Fatalw("failed_to_validate_bucket_object_lock", "err", err)
+	}
+	if output.ObjectLockConfiguration == nil || output.ObjectLockConfiguration.ObjectLockEnabled == nil ||
+		*output.ObjectLockConfiguration.ObjectLockEnabled != s3.ObjectLockEnabledEnabled {
+		lgr.Fatalw("bucket_object_lock_not_enabled", "bucket", b.bucket)
+	}
+}
+
+// sameKMSKey compares KMS key identifiers that may be given as ARNs or bare key IDs.
+func sameKMSKey(a, b string) bool {
+	if a == b {
+		return true
+	}
+	return strings.HasSuffix(a, ":key/"+b) || strings.HasSuffix(b, ":key/"+a)
+}
+
+func (b *s3Backend) PutBlob(ctx context.Context, key string, file paranoid.File, digests digest.ForUpload) error {
+	return b.uploader.UploadFile(ctx, key, file, digests)
+}
+
+func (b *s3Backend) DownloadBlob(ctx context.Context, key string, file *os.File) error {
+	getObjectInput := &s3.GetObjectInput{
+		Bucket: &b.bucket,
+		Key:    &key,
+	}
+	attempts := 0
+	for {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			zap.S().Panicw("get_blob_seek_error", "err", err)
+		}
+		if err := file.Truncate(0); err != nil {
+			zap.S().Panicw("get_blob_truncate_error", "err", err)
+		}
+		_, err := b.downloader.DownloadWithContext(ctx, file, getObjectInput)
+		if err != nil {
+			attempts++
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			if IsNoSuchKey(err) {
+				return NotFound
+			}
+			if attempts > getBlobRetriesLimit {
+				return err
+			}
+			zap.S().Errorw("get_blob_s3_error", "err", err, "attempts", attempts)
+		} else {
+			return nil
+		}
+	}
+}
+
+func (b *s3Backend) OpenBlob(ctx context.Context, key string) (io.ReadCloser, error) {
+	getObjectInput := &s3.GetObjectInput{
+		Bucket: &b.bucket,
+		Key:    &key,
+	}
+	attempts := 0
+	for {
+		getObjectOutput, err := b.s3Svc.GetObjectWithContext(ctx, getObjectInput)
+		if err != nil {
+			attempts++
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			if IsNoSuchKey(err) {
+				return nil, NotFound
+			}
+			if attempts > getBlobRetriesLimit {
+				return nil, err
+			}
+			zap.S().Errorw("open_blob_s3_error", "err", err, "attempts", attempts)
+		} else {
+			return getObjectOutput.Body, nil
+		}
+	}
+}
+
+func (b *s3Backend) HeadBlob(ctx context.Context, key string) (BlobInfo, error) {
+	headObjectInput := &s3.HeadObjectInput{
+		Bucket: &b.bucket,
+		Key:    &key,
+	}
+	headObjectOutput, err := b.s3Svc.HeadObjectWithContext(ctx, headObjectInput)
+	if err != nil {
+		if IsNoSuchKey(err) {
+			return BlobInfo{}, NotFound
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return BlobInfo{}, ctxErr
+		}
+		return BlobInfo{}, err
+	}
+	var info BlobInfo
+	if headObjectOutput.ContentLength != nil {
+		info.ContentLength = *headObjectOutput.ContentLength
+	}
+	if headObjectOutput.DeleteMarker != nil {
+		info.DeleteMarker = *headObjectOutput.DeleteMarker
+	}
+	if headObjectOutput.LastModified != nil {
+		info.LastModified = *headObjectOutput.LastModified
+	}
+	if headObjectOutput.ObjectLockRetainUntilDate != nil {
+		info.RetainUntil = *headObjectOutput.ObjectLockRetainUntilDate
+	}
+	return info, nil
+}
+
+func (b *s3Backend) BlobRetention() time.Duration {
+	if b.objectLockMode == nil {
+		return 0
+	}
+	return b.objectLockRetention
+}
+
+func (b *s3Backend) ExtendBlobRetention(ctx context.Context, key string, until time.Time) error {
+	input := &s3.PutObjectRetentionInput{
+		Bucket: &b.bucket,
+		Key:    &key,
+		Retention: &s3.ObjectLockRetention{
+			Mode:            b.objectLockMode,
+			RetainUntilDate: &until,
+		},
+	}
+	_, err := b.s3Svc.PutObjectRetentionWithContext(ctx, input)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+	}
+	return err
+}
+
+func (b *s3Backend) PutDocument(ctx context.Context, key string, document []byte) error {
+	contentMD5 := md5.Sum(document)
+	putObjectInput := &s3.PutObjectInput{
+		Bucket:               &b.bucket,
+		Key:                  &key,
+		ContentMD5:           aws.String(base64.StdEncoding.EncodeToString(contentMD5[:])),
+		ContentType:          aws.String("application/octet-stream"),
+		ServerSideEncryption: b.serverSideEncryption,
+		SSEKMSKeyId:          b.sseKMSKeyID,
+		Body:                 bytes.NewReader(document),
+	}
+	if b.objectLockMode != nil {
+		putObjectInput.ObjectLockMode = b.objectLockMode
+		putObjectInput.ObjectLockRetainUntilDate = aws.Time(time.Now().Add(b.objectLockRetention))
+	}
+	if isGzip(document) {
+		// Encrypted documents must not be labelled as gzip, or the transport will try to decompress them.
+		putObjectInput.ContentType = aws.String("application/json")
+		putObjectInput.ContentEncoding = aws.String("gzip")
+	}
+	attempts := 0
+	for {
+		_, err := b.s3Svc.PutObjectWithContext(ctx, putObjectInput)
+		if err != nil {
+			attempts++
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			if attempts > putJsonRetriesLimit {
+				return err
+			}
+			zap.S().Warnw("s3_put_object_error", "err", err, "attempts", attempts)
+			time.Sleep(time.Duration(attempts) * retrySleepPerAttempt)
+		} else {
+			return nil
+		}
+	}
+}
+
+func (b *s3Backend) GetDocument(ctx context.Context, key string) ([]byte, error) {
+	getObjectInput := &s3.GetObjectInput{
+		Bucket: &b.bucket,
+		Key:    &key,
+	}
+	attempts := 0
+	for {
+		getObjectOutput, err := b.s3Svc.GetObjectWithContext(ctx, getObjectInput)
+		if err == nil {
+			// The transport transparently removes the gzip Content-Encoding.
+			var document []byte
+			document, err = ioutil.ReadAll(getObjectOutput.Body)
+			_ = getObjectOutput.Body.Close()
+			if err == nil {
+				return document, nil
+			}
+		}
+		if IsNoSuchKey(err) {
+			return nil, NotFound
+		}
+		attempts++
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		if attempts > getJsonRetriesLimit {
+			return nil, err
+		}
+		zap.S().Warnw("s3_get_object_error", "err", err, "attempts", attempts)
+		time.Sleep(time.Duration(attempts) * retrySleepPerAttempt)
+	}
+}
+
+func (b *s3Backend) List(ctx context.Context, prefix, startAfter string, fn func(keys, prefixes []string) bool) error {
+	input := &s3.ListObjectsV2Input{
+		Bucket:    &b.bucket,
+		Delimiter: aws.String("/"),
+		Prefix:    &prefix,
+	}
+	if startAfter != "" {
+		input.StartAfter = &startAfter
+	}
+	return b.s3Svc.ListObjectsV2PagesWithContext(ctx, input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		keys := make([]string, 0, len(page.Contents))
+		for _, obj := range page.Contents {
+			keys = append(keys, *obj.Key)
+		}
+		prefixes := make([]string, 0, len(page.CommonPrefixes))
+		for _, commonPrefix := range page.CommonPrefixes {
+			prefixes = append(prefixes, *commonPrefix.Prefix)
+		}
+		return fn(keys, prefixes)
+	})
+}
+
+func (b *s3Backend) Delete(ctx context.Context, key string) error {
+	input := &s3.DeleteObjectInput{
+		Bucket: &b.bucket,
+		Key:    &key,
+	}
+	_, err := b.s3Svc.DeleteObjectWithContext(ctx, input)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if IsNoSuchKey(err) {
+			return nil
+		}
+	}
+	return err
+}
+
+func (b *s3Backend) ListVersions(ctx context.Context, prefix string, fn func(versions []ObjectVersion) bool) error {
+	input := &s3.ListObjectVersionsInput{
+		Bucket: &b.bucket,
+		Prefix: &prefix,
+	}
+	return b.s3Svc.ListObjectVersionsPagesWithContext(ctx, input, func(page *s3.ListObjectVersionsOutput, lastPage bool) bool {
+		versions := make([]ObjectVersion, 0, len(page.Versions)+len(page.DeleteMarkers))
+		for _, v := range page.Versions {
+			versions = append(versions, ObjectVersion{
+				Key:          aws.StringValue(v.Key),
+				VersionID:    aws.StringValue(v.VersionId),
+				IsLatest:     aws.BoolValue(v.IsLatest),
+				Size:         aws.Int64Value(v.Size),
+				LastModified: aws.TimeValue(v.LastModified),
+			})
+		}
+		for _, m := range page.DeleteMarkers {
+			versions = append(versions, ObjectVersion{
+				Key:          aws.StringValue(m.Key),
+				VersionID:    aws.StringValue(m.VersionId),
+				IsLatest:     aws.BoolValue(m.IsLatest),
+				DeleteMarker: true,
+				LastModified: aws.TimeValue(m.LastModified),
+			})
+		}
+		// S3 returns versions and delete markers separately, each ordered by key and then newest first.
+		sort.SliceStable(versions, func(i, j int) bool {
+			if versions[i].Key != versions[j].Key {
+				return versions[i].Key < versions[j].Key
+			}
+			return versions[i].LastModified.After(versions[j].LastModified)
+		})
+		return fn(versions)
+	})
+}
+
+func (b *s3Backend) VersionRetainUntil(ctx context.Context, key, versionID string) (time.Time, error) {
+	input := &s3.HeadObjectInput{
+		Bucket:    &b.bucket,
+		Key:       &key,
+		VersionId: &versionID,
+	}
+	output, err := b.s3Svc.HeadObjectWithContext(ctx, input)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return time.Time{}, ctxErr
+		}
+		return time.Time{}, err
+	}
+	return aws.TimeValue(output.ObjectLockRetainUntilDate), nil
+}
+
+func (b *s3Backend) DeleteVersion(ctx context.Context, key, versionID string) error {
+	input := &s3.DeleteObjectInput{
+		Bucket:    &b.bucket,
+		Key:       &key,
+		VersionId: &versionID,
+	}
+	_, err := b.s3Svc.DeleteObjectWithContext(ctx, input)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+	}
+	return err
+}
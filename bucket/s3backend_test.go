@@ -0,0 +1,105 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bucket
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+func TestSameKMSKey(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"1234abcd-12ab-34cd-56ef-1234567890ab", "1234abcd-12ab-34cd-56ef-1234567890ab", true},
+		{"arn:aws:kms:us-east-1:111122223333:key/1234abcd-12ab-34cd-56ef-1234567890ab", "1234abcd-12ab-34cd-56ef-1234567890ab", true},
+		{"1234abcd-12ab-34cd-56ef-1234567890ab", "arn:aws:kms:us-east-1:111122223333:key/1234abcd-12ab-34cd-56ef-1234567890ab", true},
+		{"arn:aws:kms:us-east-1:111122223333:key/1234abcd-12ab-34cd-56ef-1234567890ab", "0987dcba-09fe-87dc-65ba-ab0987654321", false},
+		{"alias/backups", "1234abcd-12ab-34cd-56ef-1234567890ab", false},
+	}
+	for _, c := range cases {
+		if actual := sameKMSKey(c.a, c.b); actual != c.expected {
+			t.Fatalf("a=%q b=%q expected=%v actual=%v", c.a, c.b, c.expected, actual)
+		}
+	}
+}
+
+type encryptionConfigurationS3 struct {
+	s3iface.S3API
+	output *s3.GetBucketEncryptionOutput
+	err    error
+}
+
+func (s encryptionConfigurationS3) GetBucketEncryption(*s3.GetBucketEncryptionInput) (*s3.GetBucketEncryptionOutput, error) {
+	return s.output, s.err
+}
+
+func TestValidateEncryptionConfiguration(t *testing.T) {
+	const key = "1234abcd-12ab-34cd-56ef-1234567890ab"
+	notConfigured := encryptionConfigurationS3{
+		err: awserr.New(errCodeNoEncryptionConfiguration, "", nil),
+	}
+	kmsDefault := func(keyID string) encryptionConfigurationS3 {
+		return encryptionConfigurationS3{
+			output: &s3.GetBucketEncryptionOutput{
+				ServerSideEncryptionConfiguration: &s3.ServerSideEncryptionConfiguration{
+					Rules: []*s3.ServerSideEncryptionRule{
+						{
+							ApplyServerSideEncryptionByDefault: &s3.ServerSideEncryptionByDefault{
+								SSEAlgorithm:   aws.String(s3.ServerSideEncryptionAwsKms),
+								KMSMasterKeyID: aws.String(keyID),
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	cases := []struct {
+		name           string
+		s3Svc          encryptionConfigurationS3
+		kmsKeyID       *string
+		customEndpoint bool
+		ok             bool
+		err            error
+	}{
+		{"not configured", notConfigured, nil, false, false, BucketEncryptionNotConfigured},
+		{"not configured with custom endpoint", notConfigured, nil, true, true, nil},
+		{"not configured with kms key", notConfigured, aws.String(key), true, false, BucketEncryptionNotConfigured},
+		{"matching kms key", kmsDefault("arn:aws:kms:us-east-1:111122223333:key/" + key), aws.String(key), false, true, nil},
+		{"other kms key", kmsDefault("0987dcba-09fe-87dc-65ba-ab0987654321"), aws.String(key), false, false, nil},
+		{"no rules", encryptionConfigurationS3{output: &s3.GetBucketEncryptionOutput{
+			ServerSideEncryptionConfiguration: &s3.ServerSideEncryptionConfiguration{},
+		}}, nil, false, false, BucketEncryptionNotSSE},
+	}
+	for _, c := range cases {
+		b := &s3Backend{
+			s3Svc:                c.s3Svc,
+			serverSideEncryption: aws.String(s3.ServerSideEncryptionAwsKms),
+			sseKMSKeyID:          c.kmsKeyID,
+			customEndpoint:       c.customEndpoint,
+		}
+		err := b.validateEncryptionConfiguration()
+		if (err == nil) != c.ok || (c.err != nil && err != c.err) {
+			t.Errorf("%s: err=%v", c.name, err)
+		}
+	}
+}
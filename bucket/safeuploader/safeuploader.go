@@ -20,6 +20,7 @@ import (
 	"io"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/request"
@@ -34,18 +35,31 @@ type SafeUploader struct {
 	S3                   s3iface.S3API
 	Bucket               string
 	ServerSideEncryption *string
+	SSEKMSKeyID          *string
 	StorageClass         *string
+
+	// ObjectLockMode and ObjectLockRetention, if set, lock each upload until ObjectLockRetention after it started.
+	ObjectLockMode      *string
+	ObjectLockRetention time.Duration
 }
 
 func (u *SafeUploader) UploadFile(ctx context.Context, key string, file paranoid.File, digests digest.ForUpload) error {
+	var objectLockRetainUntilDate *time.Time
+	if u.ObjectLockMode != nil {
+		objectLockRetainUntilDate = aws.Time(time.Now().Add(u.ObjectLockRetention))
+	}
 	upl := fileUploader{
 		s3Svc: u.S3,
 
 		bucket:               u.Bucket,
 		key:                  key,
 		serverSideEncryption: u.ServerSideEncryption,
+		sseKMSKeyID:          u.SSEKMSKeyID,
 		storageClass:         u.StorageClass,
 
+		objectLockMode:            u.ObjectLockMode,
+		objectLockRetainUntilDate: objectLockRetainUntilDate,
+
 		file:    file,
 		digests: digests,
 
@@ -61,8 +75,12 @@ type fileUploader struct {
 	bucket               string
 	key                  string
 	serverSideEncryption *string
+	sseKMSKeyID          *string
 	storageClass         *string
 
+	objectLockMode            *string
+	objectLockRetainUntilDate *time.Time
+
 	file    paranoid.File
 	osFile  *os.File
 	digests digest.ForUpload
@@ -99,7 +117,11 @@ func (u *fileUploader) Upload(ctx context.Context) error {
 		Bucket:               &u.bucket,
 		Key:                  &u.key,
 		ServerSideEncryption: u.serverSideEncryption,
+		SSEKMSKeyId:          u.sseKMSKeyID,
 		StorageClass:         u.storageClass,
+
+		ObjectLockMode:            u.objectLockMode,
+		ObjectLockRetainUntilDate: u.objectLockRetainUntilDate,
 	}
 	u.ctx, u.ctxCancel = context.WithCancel(ctx)
 
@@ -235,8 +257,12 @@ func (u *fileUploader) uploadSinglePart(ctx context.Context) error {
 		Key:                  &u.key,
 		ContentLength:        aws.Int64(u.digests.PartLength(1)),
 		ServerSideEncryption: u.serverSideEncryption,
+		SSEKMSKeyId:          u.sseKMSKeyID,
 		StorageClass:         u.storageClass,
 		Body:                 u.osFile,
+
+		ObjectLockMode:            u.objectLockMode,
+		ObjectLockRetainUntilDate: u.objectLockRetainUntilDate,
 	}
 	_, err := u.s3Svc.PutObjectWithContext(ctx, &putObjectInput, func(i *request.Request) {
 		i.HTTPRequest.Header.Set(md5Header, u.digests.PartContentMD5(1))
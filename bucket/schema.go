@@ -0,0 +1,85 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bucket
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"strings"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/blake2b"
+)
+
+// SchemaID returns the ID a schema document is stored under, which is derived from its contents
+// so that hosts and snapshots with the same schema share one document.
+func SchemaID(schema []byte) string {
+	sum := blake2b.Sum256(schema)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// PutSchema uploads a CQL schema script and returns its ID.
+func (c *Client) PutSchema(ctx context.Context, schema []byte) (string, error) {
+	id := SchemaID(schema)
+	var encodeBuffer bytes.Buffer
+	gzipWriter := gzip.NewWriter(&encodeBuffer)
+	if _, err := gzipWriter.Write(schema); err != nil {
+		panic(err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		panic(err)
+	}
+	if err := c.putSealedDocument(ctx, c.absoluteKeyForSchema(id), encodeBuffer.Bytes()); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// GetSchema returns NotFound if there is no schema with that ID.
+func (c *Client) GetSchema(ctx context.Context, id string) ([]byte, error) {
+	reader, err := c.openDocumentReader(ctx, c.absoluteKeyForSchema(id))
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(reader)
+}
+
+// ListSchemas calls fn with the ID of every schema in the bucket, stopping early if fn returns false.
+func (c *Client) ListSchemas(ctx context.Context, fn func(id string) bool) error {
+	prefix := c.absoluteKeyPrefixForSchemas()
+	return c.backend.List(ctx, prefix, "", func(keys, prefixes []string) bool {
+		if len(prefixes) > 0 {
+			zap.S().Warnw("unexpected_objects_in_bucket", "keys", prefixes)
+		}
+		for _, key := range keys {
+			if !fn(strings.TrimPrefix(key, prefix)) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// StatSchema returns NotFound if there is no schema with that ID.
+func (c *Client) StatSchema(ctx context.Context, id string) (BlobInfo, error) {
+	return c.backend.HeadBlob(ctx, c.absoluteKeyForSchema(id))
+}
+
+func (c *Client) DeleteSchema(ctx context.Context, id string) error {
+	return c.backend.Delete(ctx, c.absoluteKeyForSchema(id))
+}
@@ -0,0 +1,113 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bucket
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// PurgeStats counts the noncurrent versions that were (or in a dry run would have been) deleted.
+type PurgeStats struct {
+	Versions      int
+	Bytes         int64
+	DeleteMarkers int
+}
+
+// PurgeNoncurrentVersions permanently deletes the versions that a versioned bucket keeps of deleted and
+// overwritten objects once their object lock has expired, followed by delete markers that no longer
+// hide anything. Without this, deleting from a versioned bucket doesn't free any space.
+// Backends that don't keep versions have nothing to purge.
+func (c *Client) PurgeNoncurrentVersions(ctx context.Context, dryRun bool) (PurgeStats, error) {
+	var stats PurgeStats
+	versioned, ok := c.backend.(versionedBackend)
+	if !ok {
+		return stats, nil
+	}
+	lgr := zap.S()
+	now := time.Now()
+
+	purgeKey := func(versions []ObjectVersion) error {
+		var marker *ObjectVersion
+		var remaining int
+		for i, version := range versions {
+			if version.IsLatest {
+				if version.DeleteMarker {
+					marker = &versions[i]
+				} else {
+					remaining++
+				}
+				continue
+			}
+			if version.DeleteMarker {
+				stats.DeleteMarkers++
+			} else {
+				retainUntil, err := versioned.VersionRetainUntil(ctx, version.Key, version.VersionID)
+				if err != nil {
+					return err
+				}
+				if retainUntil.After(now) {
+					lgr.Debugw("purge_skipped_locked_version", "key", version.Key, "version", version.VersionID, "retain_until", retainUntil)
+					remaining++
+					continue
+				}
+				stats.Versions++
+				stats.Bytes += version.Size
+			}
+			if dryRun {
+				continue
+			}
+			if err := versioned.DeleteVersion(ctx, version.Key, version.VersionID); err != nil {
+				return err
+			}
+		}
+		if marker != nil && remaining == 0 {
+			stats.DeleteMarkers++
+			if !dryRun {
+				return versioned.DeleteVersion(ctx, marker.Key, marker.VersionID)
+			}
+		}
+		return nil
+	}
+
+	var group []ObjectVersion
+	var purgeErr error
+	err := versioned.ListVersions(ctx, c.keyWithPrefix(""), func(versions []ObjectVersion) bool {
+		for _, version := range versions {
+			if len(group) > 0 && group[0].Key != version.Key {
+				if purgeErr = purgeKey(group); purgeErr != nil {
+					return false
+				}
+				group = group[:0]
+			}
+			group = append(group, version)
+		}
+		return true
+	})
+	if purgeErr != nil {
+		return stats, purgeErr
+	}
+	if err != nil {
+		return stats, err
+	}
+	if len(group) > 0 {
+		if err := purgeKey(group); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
@@ -23,12 +23,16 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/retailnext/cassandrabackup/backup"
-	"github.com/retailnext/cassandrabackup/bucket"
 	"github.com/retailnext/cassandrabackup/cache"
-	"github.com/retailnext/cassandrabackup/manifests"
+	"github.com/retailnext/cassandrabackup/drift"
+	"github.com/retailnext/cassandrabackup/list"
 	"github.com/retailnext/cassandrabackup/periodic"
+	"github.com/retailnext/cassandrabackup/prune"
 	"github.com/retailnext/cassandrabackup/restore"
-	"github.com/retailnext/cassandrabackup/unixtime"
+	"github.com/retailnext/cassandrabackup/schema"
+	"github.com/retailnext/cassandrabackup/show"
+	"github.com/retailnext/cassandrabackup/verify"
+	"github.com/retailnext/cassandrabackup/version"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/ssh/terminal"
 	"gopkg.in/alecthomas/kingpin.v2"
@@ -107,21 +111,11 @@ var (
 
 	metricsListenAddress = kingpin.Flag("web.listen-address", "Address on which to expose metrics.").String()
 	metricsPath          = kingpin.Flag("web.telemetry-path", "Path under which to expose metrics.").Default("/metrics").String()
-
-	listCmd = kingpin.Command("list", "")
-
-	listManifestsCmd          = listCmd.Command("manifests", "List manifests for a host")
-	listManifestsCmdCluster   = listManifestsCmd.Flag("cluster", "Cluster name to restore from").Required().String()
-	listManifestsCmdHostname  = listManifestsCmd.Flag("hostname", "Hostname to restore from").Required().String()
-	listManifestsCmdNotBefore = listManifestsCmd.Flag("not-before", "Ignore manifests before this time (unix seconds)").Int64()
-	listManifestsCmdNotAfter  = listManifestsCmd.Flag("not-after", "Ignore manifests after this time (unix seconds)").Int64()
-
-	listHostsCmd        = listCmd.Command("hosts", "List hosts in a cluster")
-	listHostsCmdCluster = listHostsCmd.Flag("cluster", "Cluster name").Required().String()
 )
 
 func main() {
 	kingpin.UsageTemplate(kingpin.CompactUsageTemplate)
+	kingpin.Version(version.Version)
 	cmd := kingpin.Parse()
 
 	sync := setupLogger()
@@ -161,6 +155,14 @@ func main() {
 		if err != nil {
 			lgr.Fatalw("backup_error", "err", err)
 		}
+	case "backup commitlog":
+		err := backup.DoCommitLog(ctx)
+		if err == context.Canceled {
+			return
+		}
+		if err != nil {
+			lgr.Fatalw("backup_error", "err", err)
+		}
 	case "backup run":
 		err := periodic.Main(ctx)
 		if err == context.Canceled {
@@ -185,29 +187,93 @@ func main() {
 		if err != nil {
 			lgr.Fatalw("restore_error", "err", err)
 		}
+	case "restore live":
+		err := restore.RestoreLive(ctx)
+		if err == context.Canceled {
+			return
+		}
+		if err != nil {
+			lgr.Fatalw("restore_error", "err", err)
+		}
+	case "restore schema":
+		err := restore.RestoreSchema(ctx)
+		if err == context.Canceled {
+			return
+		}
+		if err != nil {
+			lgr.Fatalw("restore_error", "err", err)
+		}
+	case "schema apply":
+		err := schema.Apply(ctx)
+		if err == context.Canceled {
+			return
+		}
+		if err != nil {
+			lgr.Fatalw("schema_apply_error", "err", err)
+		}
+	case "drift":
+		err := drift.Main(ctx)
+		if err == context.Canceled {
+			return
+		}
+		if err != nil {
+			lgr.Fatalw("drift_error", "err", err)
+		}
+	case "prune":
+		err := prune.Main(ctx)
+		if err == context.Canceled {
+			return
+		}
+		if err != nil {
+			lgr.Fatalw("prune_error", "err", err)
+		}
+	case "verify":
+		err := verify.Main(ctx)
+		if err == context.Canceled {
+			return
+		}
+		if err != nil {
+			lgr.Fatalw("verify_error", "err", err)
+		}
+	case "list clusters":
+		err := list.Clusters(ctx)
+		if err == context.Canceled {
+			return
+		}
+		if err != nil {
+			lgr.Fatalw("list_clusters_error", "err", err)
+		}
 	case "list manifests":
-		lgr := zap.S()
-		identity := manifests.NodeIdentity{
-			Cluster:  *listManifestsCmdCluster,
-			Hostname: *listManifestsCmdHostname,
+		err := list.Manifests(ctx)
+		if err == context.Canceled {
+			return
 		}
-		bkt := bucket.OpenShared()
-		manifestKeys, err := bkt.ListManifests(ctx, identity, unixtime.Seconds(*listManifestsCmdNotBefore), unixtime.Seconds(*listManifestsCmdNotAfter))
 		if err != nil {
 			lgr.Fatalw("list_manifests_error", "err", err)
 		}
-		for _, mk := range manifestKeys {
-			lgr.Infow("got_manifest", "manifest", mk)
-		}
 	case "list hosts":
-		lgr := zap.S()
-		bkt := bucket.OpenShared()
-		results, err := bkt.ListHostNames(ctx, *listHostsCmdCluster)
+		err := list.Hosts(ctx)
+		if err == context.Canceled {
+			return
+		}
 		if err != nil {
 			lgr.Fatalw("list_hosts_error", "err", err)
 		}
-		for _, ni := range results {
-			lgr.Infow("got_host", "identity", ni)
+	case "show manifest":
+		err := show.Manifest(ctx)
+		if err == context.Canceled {
+			return
+		}
+		if err != nil {
+			lgr.Fatalw("show_manifest_error", "err", err)
+		}
+	case "diff manifests":
+		err := show.DiffManifests(ctx)
+		if err == context.Canceled {
+			return
+		}
+		if err != nil {
+			lgr.Fatalw("diff_manifests_error", "err", err)
 		}
 	default:
 		lgr.Fatalw("unhandled_command", "cmd", cmd)
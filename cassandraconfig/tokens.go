@@ -0,0 +1,116 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cassandraconfig
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/retailnext/cassandrabackup/writefile"
+)
+
+var tokenSettingExpr = regexp.MustCompile(`^(initial_token|num_tokens)\s*:`)
+
+// WriteTokens sets initial_token and num_tokens in ConfigFileName, keeping the rest of the file as it
+// is. The original is copied next to it first, and the name of the copy is returned.
+func WriteTokens(tokens []string) (string, error) {
+	return writeTokens(ConfigFileName, tokens)
+}
+
+func writeTokens(configFile string, tokens []string) (string, error) {
+	configFile, err := filepath.Abs(configFile)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(configFile)
+	if err != nil {
+		return "", err
+	}
+	dirInfo, err := os.Stat(filepath.Dir(configFile))
+	if err != nil {
+		return "", err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		panic("cassandraconfig: unable to check file ownership")
+	}
+	existing, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return "", err
+	}
+
+	// Both files are replaced by rename so cassandra never sees a partial config, and they keep the
+	// original's mode and owner since the config may hold secrets like keystore passwords.
+	target := writefile.Config{
+		Directory:           filepath.Dir(configFile),
+		DirectoryMode:       dirInfo.Mode().Perm(),
+		FileMode:            info.Mode().Perm(),
+		FileUID:             int(stat.Uid),
+		FileGID:             int(stat.Gid),
+		EnsureFileOwnership: true,
+	}
+	write := func(name string, contents []byte) error {
+		return target.WriteFile(name, func(file *os.File) error {
+			if _, err := file.Write(contents); err != nil {
+				return err
+			}
+			return file.Sync()
+		})
+	}
+
+	backupName := fmt.Sprintf("%s.%d.bak", filepath.Base(configFile), time.Now().Unix())
+	if err := write(backupName, existing); err != nil {
+		return "", err
+	}
+	backupName = filepath.Join(target.Directory, backupName)
+	return backupName, write(filepath.Base(configFile), []byte(withTokens(string(existing), tokens)))
+}
+
+func withTokens(existing string, tokens []string) string {
+	settings := map[string]string{
+		"initial_token": strings.Join(tokens, ","),
+		"num_tokens":    strconv.Itoa(len(tokens)),
+	}
+	lines := strings.Split(existing, "\n")
+	for i, line := range lines {
+		match := tokenSettingExpr.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		if value, ok := settings[match[1]]; ok {
+			lines[i] = match[1] + ": " + value
+			delete(settings, match[1])
+		} else {
+			// Cassandra would reject a duplicate setting anyway.
+			lines[i] = "# " + line
+		}
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	for _, key := range []string{"num_tokens", "initial_token"} {
+		if value, ok := settings[key]; ok {
+			lines = append(lines, key+": "+value)
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,122 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cassandraconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-test/deep"
+	"gopkg.in/yaml.v2"
+)
+
+func TestWithTokens(t *testing.T) {
+	existing := `cluster_name: 'Test Cluster'
+
+# This defines the number of tokens randomly assigned to this node on the ring
+num_tokens: 256
+
+# initial_token allows you to specify tokens manually.
+# initial_token:
+
+seed_provider:
+    - class_name: org.apache.cassandra.locator.SimpleSeedProvider
+      parameters:
+          - seeds: "127.0.0.1"
+`
+	expected := `cluster_name: 'Test Cluster'
+
+# This defines the number of tokens randomly assigned to this node on the ring
+num_tokens: 2
+
+# initial_token allows you to specify tokens manually.
+# initial_token:
+
+seed_provider:
+    - class_name: org.apache.cassandra.locator.SimpleSeedProvider
+      parameters:
+          - seeds: "127.0.0.1"
+initial_token: -9223372036854775808,5
+`
+	updated := withTokens(existing, []string{"-9223372036854775808", "5"})
+	if diff := deep.Equal(updated, expected); diff != nil {
+		t.Fatal(diff)
+	}
+
+	var raw Raw
+	if err := yaml.Unmarshal([]byte(updated), &raw); err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(raw.Tokens(), []string{"-9223372036854775808", "5"}); diff != nil {
+		t.Fatal(diff)
+	}
+	if diff := deep.Equal(raw.ClusterName, "Test Cluster"); diff != nil {
+		t.Fatal(diff)
+	}
+
+	// Rewriting is idempotent and replaces existing values.
+	if diff := deep.Equal(withTokens(withTokens(updated, []string{"1"}), []string{"1"}), withTokens(updated, []string{"1"})); diff != nil {
+		t.Fatal(diff)
+	}
+}
+
+func TestWriteTokens(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cassandraconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	configFile := filepath.Join(dir, "cassandra.yaml")
+	existing := "cluster_name: 'Test Cluster'\nnum_tokens: 256\n"
+	if err := ioutil.WriteFile(configFile, []byte(existing), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	backupName, err := writeTokens(configFile, []string{"-3", "7"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, expected := range map[string]string{
+		configFile: "cluster_name: 'Test Cluster'\nnum_tokens: 2\ninitial_token: -3,7\n",
+		backupName: existing,
+	} {
+		contents, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := deep.Equal(string(contents), expected); diff != nil {
+			t.Error(name, diff)
+		}
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("%s: mode %v", name, info.Mode())
+		}
+	}
+
+	leftovers, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leftovers) != 2 {
+		t.Errorf("unexpected files: %v", leftovers)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cassandraversion finds and compares Cassandra release versions.
+package cassandraversion
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+var Binary = "/usr/sbin/cassandra"
+
+// Installed returns the release version of the installed Cassandra, which need not be running.
+func Installed() (string, error) {
+	output, err := exec.Command(Binary, "-v").Output()
+	if err != nil {
+		zap.S().Errorw("cassandra_version_fail", "err", err, "output", output)
+		return "", err
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	return strings.TrimSpace(lines[len(lines)-1]), nil
+}
+
+// Compare returns -1, 0 or 1 if release version a is older than, the same as or newer than b.
+// Numeric components are compared numerically, and suffixes like "-SNAPSHOT" are ignored.
+func Compare(a, b string) int {
+	aParts := numericParts(a)
+	bParts := numericParts(b)
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var aPart, bPart int
+		if i < len(aParts) {
+			aPart = aParts[i]
+		}
+		if i < len(bParts) {
+			bPart = bParts[i]
+		}
+		switch {
+		case aPart < bPart:
+			return -1
+		case aPart > bPart:
+			return 1
+		}
+	}
+	return 0
+}
+
+func numericParts(version string) []int {
+	if i := strings.IndexAny(version, "-+ "); i >= 0 {
+		version = version[:i]
+	}
+	var result []int
+	for _, part := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			break
+		}
+		result = append(result, n)
+	}
+	return result
+}
@@ -0,0 +1,36 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cassandraversion
+
+import "testing"
+
+func TestCompare(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"3.11.4", "3.11.4", 0},
+		{"3.11.4", "3.11.10", -1},
+		{"4.0.0", "3.11.10", 1},
+		{"4.0", "4.0.0", 0},
+		{"4.0-beta4", "4.0.0", 0},
+		{"3.0.19-SNAPSHOT", "3.0.20", -1},
+	}
+	for _, c := range cases {
+		if actual := Compare(c.a, c.b); actual != c.expected {
+			t.Fatalf("a=%q b=%q expected=%d actual=%d", c.a, c.b, c.expected, actual)
+		}
+	}
+}
@@ -29,8 +29,13 @@ type Cache struct {
 
 func OpenShared() *Cache {
 	cache.OpenShared()
+	return NewCache(cache.Shared)
+}
+
+// NewCache returns a Cache that keeps digests in storage.
+func NewCache(storage *cache.Storage) *Cache {
 	return &Cache{
-		c: cache.Shared.Cache(cacheName),
+		c: storage.Cache(cacheName),
 	}
 }
 
@@ -37,7 +37,11 @@ func (r ForRestore) Verify(ctx context.Context, reader io.ReadSeeker) error {
 	if err != nil {
 		return err
 	}
+	return r.VerifyStream(ctx, reader)
+}
 
+// VerifyStream is like Verify, but reads from the current position of reader to the end.
+func (r ForRestore) VerifyStream(ctx context.Context, reader io.Reader) error {
 	blake2b512Hash, err := blake2b.New512(nil)
 	if err != nil {
 		panic(err)
@@ -0,0 +1,29 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drift
+
+import "gopkg.in/alecthomas/kingpin.v2"
+
+var (
+	Cmd = kingpin.Command("drift", "Compare this host's data directory against what restoring its backup would produce")
+
+	cmdDataDirectory   = Cmd.Flag("data-directory", "Cassandra data directory to compare.").Default("/var/lib/cassandra/data").String()
+	cmdNotBefore       = Cmd.Flag("not-before", "Ignore manifests before this time (unix seconds)").Int64()
+	cmdNotAfter        = Cmd.Flag("not-after", "Ignore manifests after this time (unix seconds)").Int64()
+	cmdCluster         = Cmd.Flag("cluster", "Use a different cluster name when selecting a backup to compare against.").String()
+	cmdHostname        = Cmd.Flag("hostname", "Use a specific hostname when selecting a backup to compare against.").String()
+	cmdHostnamePattern = Cmd.Flag("hostname-pattern", "Use a prefix pattern when selecting a backup to compare against.").String()
+	cmdShowFiles       = Cmd.Flag("show-files", "Log every missing, extra and differing file, not just the per-table summary.").Bool()
+)
@@ -0,0 +1,201 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drift
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/retailnext/cassandrabackup/digest"
+	"github.com/retailnext/cassandrabackup/manifests"
+	"github.com/retailnext/cassandrabackup/nodeidentity"
+	"github.com/retailnext/cassandrabackup/paranoid"
+	"github.com/retailnext/cassandrabackup/restore/plan"
+	"github.com/retailnext/cassandrabackup/unixtime"
+	"go.uber.org/zap"
+)
+
+var NoBackupsFound = errors.New("no backups found for host")
+var DriftDetected = errors.New("data directory differs from backup")
+
+// TableSummary counts how the files of one table differ between the backup and the data directory.
+type TableSummary struct {
+	Table     string
+	Matching  int
+	Missing   []string
+	Extra     []string
+	Differing []string
+}
+
+func (s TableSummary) Drifted() bool {
+	return len(s.Missing) > 0 || len(s.Extra) > 0 || len(s.Differing) > 0
+}
+
+func Main(ctx context.Context) error {
+	identity := nodeidentity.ForRestore(ctx, cmdCluster, cmdHostname, cmdHostnamePattern)
+	lgr := zap.S().With("identity", identity)
+
+	nodePlan, err := plan.Create(ctx, identity, unixtime.Seconds(*cmdNotBefore), unixtime.Seconds(*cmdNotAfter))
+	if err != nil {
+		return err
+	}
+	if len(nodePlan.SelectedManifests) == 0 {
+		return NoBackupsFound
+	}
+	lgr.Infow("selected_manifests", "base", nodePlan.SelectedManifests[0], "additional", nodePlan.SelectedManifests[1:])
+
+	backedUp, err := manifests.NewTableFilter(nil, nodePlan.ExcludedTables, true)
+	if err != nil {
+		return err
+	}
+	live, err := liveFiles(ctx, *cmdDataDirectory, digest.OpenShared())
+	if err != nil {
+		return err
+	}
+	live = withoutUnbackedTables(nodePlan.Files, live, backedUp)
+
+	drifted := false
+	for _, summary := range compare(nodePlan.Files, live) {
+		lgr.Infow("drift_table", "table", summary.Table, "matching", summary.Matching, "missing", len(summary.Missing), "extra", len(summary.Extra), "differing", len(summary.Differing))
+		if *cmdShowFiles {
+			for _, name := range summary.Missing {
+				lgr.Infow("drift_missing_file", "name", name)
+			}
+			for _, name := range summary.Extra {
+				lgr.Infow("drift_extra_file", "name", name)
+			}
+			for _, name := range summary.Differing {
+				lgr.Infow("drift_differing_file", "name", name)
+			}
+		}
+		drifted = drifted || summary.Drifted()
+	}
+	if drifted {
+		return DriftDetected
+	}
+	return nil
+}
+
+// liveFiles digests the live sstables in directory, keyed the same way as manifests.
+// Files in backups and snapshots directories are skipped.
+func liveFiles(ctx context.Context, directory string, digestCache *digest.Cache) (map[string]digest.ForRestore, error) {
+	lgr := zap.S()
+	result := make(map[string]digest.ForRestore)
+	walkErr := filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				// Compacted away mid-walk.
+				return nil
+			}
+			return err
+		}
+		relPath, err := filepath.Rel(directory, path)
+		if err != nil {
+			panic(err)
+		}
+		parts := strings.Split(relPath, string(filepath.Separator))
+		if info.IsDir() {
+			if len(parts) == 3 && (parts[2] == "backups" || parts[2] == "snapshots") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if len(parts) < 3 {
+			return nil
+		}
+
+		forUpload, err := digestCache.Get(ctx, paranoid.NewFileFromInfo(path, info))
+		if err != nil {
+			if os.IsNotExist(err) {
+				lgr.Debugw("drift_file_vanished", "path", path)
+				return nil
+			}
+			return err
+		}
+		result[filepath.ToSlash(relPath)] = forUpload.ForRestore()
+		return nil
+	})
+	return result, walkErr
+}
+
+// withoutUnbackedTables drops the live files of tables that the backup deliberately left out: those
+// that backedUp doesn't match, and system tables that the backup has no files for.
+func withoutUnbackedTables(planned, live map[string]digest.ForRestore, backedUp manifests.TableFilter) map[string]digest.ForRestore {
+	plannedTables := make(map[string]struct{})
+	for name := range planned {
+		plannedTables[manifests.TableName(name)] = struct{}{}
+	}
+	result := make(map[string]digest.ForRestore, len(live))
+	for name, digests := range live {
+		if !backedUp.MatchPath(name) {
+			continue
+		}
+		if keyspace := strings.SplitN(name, "/", 2)[0]; manifests.IsSystemKeyspace(keyspace) {
+			if _, ok := plannedTables[manifests.TableName(name)]; !ok {
+				continue
+			}
+		}
+		result[name] = digests
+	}
+	return result
+}
+
+// compare returns per-table summaries, sorted by table, of how live differs from planned.
+func compare(planned, live map[string]digest.ForRestore) []TableSummary {
+	return summarize(planned, manifests.DiffDataFiles(planned, live))
+}
+
+// summarize groups the changes from planned by table. Planned files without changes count as matching.
+func summarize(planned map[string]digest.ForRestore, changes []manifests.FileChange) []TableSummary {
+	summaries := make(map[string]*TableSummary)
+	summary := func(table string) *TableSummary {
+		s, ok := summaries[table]
+		if !ok {
+			s = &TableSummary{Table: table}
+			summaries[table] = s
+		}
+		return s
+	}
+
+	for name := range planned {
+		summary(manifests.TableName(name)).Matching++
+	}
+	for _, change := range changes {
+		s := summary(change.Table)
+		switch change.Change {
+		case manifests.FileRemoved:
+			s.Missing = append(s.Missing, change.Path)
+			s.Matching--
+		case manifests.FileChanged:
+			s.Differing = append(s.Differing, change.Path)
+			s.Matching--
+		case manifests.FileAdded:
+			s.Extra = append(s.Extra, change.Path)
+		}
+	}
+
+	result := make([]TableSummary, 0, len(summaries))
+	for _, s := range summaries {
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Table < result[j].Table
+	})
+	return result
+}
@@ -0,0 +1,87 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drift
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/retailnext/cassandrabackup/digest"
+	"github.com/retailnext/cassandrabackup/manifests"
+)
+
+func TestSummarize(t *testing.T) {
+	planned := map[string]digest.ForRestore{
+		"ks/t1-0123/md-1-big-Data.db":         {},
+		"ks/t1-0123/md-2-big-Data.db":         {},
+		"ks/t1-0123/.t1_idx/md-1-big-Data.db": {},
+		"ks/t2-4567/md-1-big-Data.db":         {},
+	}
+	changes := []manifests.FileChange{
+		{Table: "ks.t1", Path: "ks/t1-0123/.t1_idx/md-1-big-Data.db", Change: manifests.FileChanged},
+		{Table: "ks.t1", Path: "ks/t1-0123/md-2-big-Data.db", Change: manifests.FileRemoved},
+		{Table: "ks.t2", Path: "ks/t2-4567/md-3-big-Data.db", Change: manifests.FileAdded},
+	}
+	expected := []TableSummary{
+		{
+			Table:     "ks.t1",
+			Matching:  1,
+			Missing:   []string{"ks/t1-0123/md-2-big-Data.db"},
+			Differing: []string{"ks/t1-0123/.t1_idx/md-1-big-Data.db"},
+		},
+		{
+			Table:    "ks.t2",
+			Matching: 1,
+			Extra:    []string{"ks/t2-4567/md-3-big-Data.db"},
+		},
+	}
+	if diff := deep.Equal(summarize(planned, changes), expected); diff != nil {
+		t.Fatal(diff)
+	}
+}
+
+func TestWithoutUnbackedTables(t *testing.T) {
+	var a digest.ForRestore
+	planned := map[string]digest.ForRestore{
+		"ks/t1-0123/md-1-big-Data.db":             a,
+		"system_auth/roles-5bc5/md-1-big-Data.db": a,
+	}
+	live := map[string]digest.ForRestore{
+		"ks/t1-0123/md-1-big-Data.db":                a,
+		"ks/t1-0123/md-2-big-Data.db":                a,
+		"scratch/t3-89ab/md-1-big-Data.db":           a,
+		"system_auth/roles-5bc5/md-1-big-Data.db":    a,
+		"system/local-7ad5/md-1-big-Data.db":         a,
+		"system_schema/tables-afdd/md-1-big-Data.db": a,
+	}
+	backedUp, err := manifests.NewTableFilter(nil, []string{"scratch"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []TableSummary{
+		{
+			Table:    "ks.t1",
+			Matching: 1,
+			Extra:    []string{"ks/t1-0123/md-2-big-Data.db"},
+		},
+		{
+			Table:    "system_auth.roles",
+			Matching: 1,
+		},
+	}
+	if diff := deep.Equal(compare(planned, withoutUnbackedTables(planned, live, backedUp)), expected); diff != nil {
+		t.Fatal(diff)
+	}
+}
@@ -0,0 +1,273 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package envelope implements client side envelope encryption.
+//
+// Each message is encrypted with its own random data key, which is stored alongside it wrapped by a master key.
+// The wrapping authenticates the master key's ID and a caller supplied context, such as the name the message is
+// stored under, so a message can't be passed off as another one.
+//
+// Messages are split into fixed size chunks sealed with AES-256-GCM so that they can be streamed, with the
+// final chunk marked so that truncation is detected.
+package envelope
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+const (
+	keyLength   = 32
+	keyIDLength = 8
+	nonceLength = 12
+	tagLength   = 16
+	chunkLength = 64 * 1024
+
+	// HeaderLength is the number of bytes before the first chunk.
+	HeaderLength = len(magic) + keyIDLength + nonceLength + keyLength + tagLength
+)
+
+const magic = "cbe1"
+
+var (
+	NotEncrypted = errors.New("envelope: not encrypted")
+	WrongKey     = errors.New("envelope: encrypted with a different master key")
+	WrongContext = errors.New("envelope: data key could not be unwrapped for this context")
+	Corrupt      = errors.New("envelope: corrupt or truncated")
+)
+
+// IsInvalid reports whether err means that a message can't be decrypted as given, as opposed to
+// it failing to be read or written.
+func IsInvalid(err error) bool {
+	switch err {
+	case NotEncrypted, WrongKey, WrongContext, Corrupt:
+		return true
+	}
+	return false
+}
+
+type Key struct {
+	id   []byte
+	aead cipher.AEAD
+}
+
+func NewKey(master []byte) (*Key, error) {
+	if len(master) != keyLength {
+		return nil, fmt.Errorf("envelope: master key must be %d bytes", keyLength)
+	}
+	aead, err := newAEAD(master)
+	if err != nil {
+		return nil, err
+	}
+	id := sha256.Sum256(append([]byte("cassandrabackup envelope key id\x00"), master...))
+	return &Key{id: id[:keyIDLength], aead: aead}, nil
+}
+
+// ID identifies the master key without revealing it.
+func (k *Key) ID() string {
+	return hex.EncodeToString(k.id)
+}
+
+// LoadKeyFile reads a master key file containing either 32 raw bytes or their base64 encoding.
+func LoadKeyFile(name string) (*Key, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == keyLength {
+		return NewKey(data)
+	}
+	master, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(data)))
+	if err != nil {
+		return nil, fmt.Errorf("envelope: invalid key file: %v", err)
+	}
+	return NewKey(master)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// IsEncrypted reports whether data starts with an envelope header.
+func IsEncrypted(data []byte) bool {
+	return len(data) >= len(magic) && string(data[:len(magic)]) == magic
+}
+
+// CiphertextLength returns the encrypted length of a message of plaintextLength bytes.
+func CiphertextLength(plaintextLength int64) int64 {
+	chunks := (plaintextLength + chunkLength - 1) / chunkLength
+	if chunks == 0 {
+		chunks = 1
+	}
+	return int64(HeaderLength) + plaintextLength + chunks*tagLength
+}
+
+func chunkNonce(counter uint64, last bool) []byte {
+	nonce := make([]byte, nonceLength)
+	binary.BigEndian.PutUint64(nonce, counter)
+	if last {
+		nonce[nonceLength-1] = 1
+	}
+	return nonce
+}
+
+// wrapAdditionalData returns what the data key wrapping authenticates: the header up to the nonce and the context.
+func wrapAdditionalData(header, context []byte) []byte {
+	ad := make([]byte, 0, len(magic)+keyIDLength+len(context))
+	ad = append(ad, header[:len(magic)+keyIDLength]...)
+	return append(ad, context...)
+}
+
+// Encrypt writes the encryption of everything read from src to dst. The same context must be given to Decrypt.
+func (k *Key) Encrypt(dst io.Writer, src io.Reader, context []byte) error {
+	dataKey := make([]byte, keyLength)
+	if _, err := rand.Read(dataKey); err != nil {
+		return err
+	}
+	wrapNonce := make([]byte, nonceLength)
+	if _, err := rand.Read(wrapNonce); err != nil {
+		return err
+	}
+
+	header := make([]byte, 0, HeaderLength)
+	header = append(header, magic...)
+	header = append(header, k.id...)
+	header = append(header, wrapNonce...)
+	header = k.aead.Seal(header, wrapNonce, dataKey, wrapAdditionalData(header, context))
+	if _, err := dst.Write(header); err != nil {
+		return err
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return err
+	}
+	reader := bufio.NewReaderSize(src, chunkLength)
+	plaintext := make([]byte, chunkLength)
+	ciphertext := make([]byte, 0, chunkLength+tagLength)
+	for counter := uint64(0); ; counter++ {
+		n, err := io.ReadFull(reader, plaintext)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		last := n < chunkLength
+		if !last {
+			if _, peekErr := reader.Peek(1); peekErr == io.EOF {
+				last = true
+			} else if peekErr != nil {
+				return peekErr
+			}
+		}
+		ciphertext = aead.Seal(ciphertext[:0], chunkNonce(counter, last), plaintext[:n], nil)
+		if _, err := dst.Write(ciphertext); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// Decrypt writes the decryption of everything read from src to dst.
+// Partial plaintext may be written to dst before an error is detected.
+func (k *Key) Decrypt(dst io.Writer, src io.Reader, context []byte) error {
+	reader := bufio.NewReaderSize(src, chunkLength+tagLength)
+
+	header := make([]byte, HeaderLength)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return NotEncrypted
+		}
+		return err
+	}
+	if !IsEncrypted(header) {
+		return NotEncrypted
+	}
+	if !bytes.Equal(header[len(magic):len(magic)+keyIDLength], k.id) {
+		return WrongKey
+	}
+	wrapNonce := header[len(magic)+keyIDLength : len(magic)+keyIDLength+nonceLength]
+	dataKey, err := k.aead.Open(nil, wrapNonce, header[len(magic)+keyIDLength+nonceLength:], wrapAdditionalData(header, context))
+	if err != nil {
+		return WrongContext
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return Corrupt
+	}
+
+	ciphertext := make([]byte, chunkLength+tagLength)
+	plaintext := make([]byte, 0, chunkLength)
+	for counter := uint64(0); ; counter++ {
+		n, err := io.ReadFull(reader, ciphertext)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		if n < tagLength {
+			return Corrupt
+		}
+		last := n < len(ciphertext)
+		if !last {
+			if _, peekErr := reader.Peek(1); peekErr == io.EOF {
+				last = true
+			} else if peekErr != nil {
+				return peekErr
+			}
+		}
+		plaintext, err = aead.Open(plaintext[:0], chunkNonce(counter, last), ciphertext[:n], nil)
+		if err != nil {
+			return Corrupt
+		}
+		if _, err := dst.Write(plaintext); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// Seal returns the encryption of plaintext.
+func (k *Key) Seal(plaintext, context []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Grow(int(CiphertextLength(int64(len(plaintext)))))
+	if err := k.Encrypt(&buf, bytes.NewReader(plaintext), context); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Open returns the decryption of ciphertext.
+func (k *Key) Open(ciphertext, context []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := k.Decrypt(&buf, bytes.NewReader(ciphertext), context); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
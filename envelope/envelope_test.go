@@ -0,0 +1,108 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envelope
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+var testContext = []byte("files/blake2b/a/b/c")
+
+func newTestKey() *Key {
+	master := make([]byte, keyLength)
+	if _, err := rand.Read(master); err != nil {
+		panic(err)
+	}
+	key, err := NewKey(master)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+func TestRoundTrip(t *testing.T) {
+	key := newTestKey()
+	for _, length := range []int{0, 1, chunkLength - 1, chunkLength, chunkLength + 1, 3 * chunkLength} {
+		plaintext := make([]byte, length)
+		if _, err := rand.Read(plaintext); err != nil {
+			panic(err)
+		}
+		ciphertext, err := key.Seal(plaintext, testContext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int64(len(ciphertext)) != CiphertextLength(int64(length)) {
+			t.Fatalf("length=%d expected=%d actual=%d", length, CiphertextLength(int64(length)), len(ciphertext))
+		}
+		if !IsEncrypted(ciphertext) {
+			t.Fatalf("length=%d not recognized as encrypted", length)
+		}
+		opened, err := key.Open(ciphertext, testContext)
+		if err != nil {
+			t.Fatalf("length=%d err=%v", length, err)
+		}
+		if !bytes.Equal(opened, plaintext) {
+			t.Fatalf("length=%d plaintext mismatch", length)
+		}
+	}
+}
+
+func TestTampering(t *testing.T) {
+	key := newTestKey()
+	plaintext := make([]byte, 2*chunkLength)
+	ciphertext, err := key.Seal(plaintext, testContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := newTestKey().Open(ciphertext, testContext); err != WrongKey {
+		t.Fatalf("wrong key: expected=%v actual=%v", WrongKey, err)
+	}
+	if _, err := key.Open(ciphertext, []byte("files/blake2b/a/b/d")); err != WrongContext {
+		t.Fatalf("wrong context: expected=%v actual=%v", WrongContext, err)
+	}
+
+	// The key ID is authenticated too, not just compared.
+	relabeled := append([]byte{}, ciphertext...)
+	relabeled[len(magic)] ^= 1
+	relabeledKey := *key
+	relabeledKey.id = relabeled[len(magic) : len(magic)+keyIDLength]
+	if _, err := relabeledKey.Open(relabeled, testContext); err != WrongContext {
+		t.Fatalf("relabeled: expected=%v actual=%v", WrongContext, err)
+	}
+
+	if _, err := key.Open(plaintext, testContext); err != NotEncrypted {
+		t.Fatalf("plaintext: expected=%v actual=%v", NotEncrypted, err)
+	}
+
+	// Dropping the whole final chunk must not go unnoticed.
+	truncated := ciphertext[:HeaderLength+chunkLength+tagLength]
+	if _, err := key.Open(truncated, testContext); err != Corrupt {
+		t.Fatalf("truncated: expected=%v actual=%v", Corrupt, err)
+	}
+
+	appended := append(append([]byte{}, ciphertext...), 0)
+	if _, err := key.Open(appended, testContext); err != Corrupt {
+		t.Fatalf("appended: expected=%v actual=%v", Corrupt, err)
+	}
+
+	flipped := append([]byte{}, ciphertext...)
+	flipped[HeaderLength+10] ^= 1
+	if _, err := key.Open(flipped, testContext); err != Corrupt {
+		t.Fatalf("flipped: expected=%v actual=%v", Corrupt, err)
+	}
+}
@@ -1,13 +1,14 @@
 module github.com/retailnext/cassandrabackup
 
 require (
-	github.com/alecthomas/units v0.0.0-20190924025748-f65c72e2690d // indirect
+	github.com/alecthomas/units v0.0.0-20190924025748-f65c72e2690d
 	github.com/aws/aws-sdk-go v1.29.1
 	github.com/go-test/deep v1.0.5
 	github.com/gocql/gocql v0.0.0-20200203083758-81b8263d9fe5
 	github.com/golang/snappy v0.0.1 // indirect
 	github.com/mailru/easyjson v0.7.0
 	github.com/prometheus/client_golang v1.4.1
+	github.com/prometheus/common v0.9.1
 	go.etcd.io/bbolt v1.3.3
 	go.uber.org/atomic v1.5.1 // indirect
 	go.uber.org/multierr v1.4.0 // indirect
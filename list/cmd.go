@@ -0,0 +1,39 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package list
+
+import (
+	"github.com/retailnext/cassandrabackup/output"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	Cmd = kingpin.Command("list", "")
+
+	ClustersCmd       = Cmd.Command("clusters", "List clusters with backups")
+	clustersCmdOutput = output.Flag(ClustersCmd)
+
+	ManifestsCmd          = Cmd.Command("manifests", "List manifests for a host")
+	manifestsCmdCluster   = ManifestsCmd.Flag("cluster", "Cluster name to restore from").Required().String()
+	manifestsCmdHostname  = ManifestsCmd.Flag("hostname", "Hostname to restore from").Required().String()
+	manifestsCmdNotBefore = ManifestsCmd.Flag("not-before", "Ignore manifests before this time (unix seconds)").Int64()
+	manifestsCmdNotAfter  = ManifestsCmd.Flag("not-after", "Ignore manifests after this time (unix seconds)").Int64()
+	manifestsCmdOutput    = output.Flag(ManifestsCmd)
+
+	HostsCmd        = Cmd.Command("hosts", "List hosts in a cluster")
+	hostsCmdCluster = HostsCmd.Flag("cluster", "Cluster name").Required().String()
+	hostsCmdDetails = HostsCmd.Flag("details", "Include the latest backup times and details of each host's newest manifest.").Bool()
+	hostsCmdOutput  = output.Flag(HostsCmd)
+)
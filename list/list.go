@@ -0,0 +1,181 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package list
+
+import (
+	"context"
+	"os"
+
+	"github.com/retailnext/cassandrabackup/bucket"
+	"github.com/retailnext/cassandrabackup/manifests"
+	"github.com/retailnext/cassandrabackup/output"
+	"github.com/retailnext/cassandrabackup/unixtime"
+)
+
+func Manifests(ctx context.Context) error {
+	identity := manifests.NodeIdentity{
+		Cluster:  *manifestsCmdCluster,
+		Hostname: *manifestsCmdHostname,
+	}
+	client := bucket.OpenShared()
+	keys, err := client.ListManifests(ctx, identity, unixtime.Seconds(*manifestsCmdNotBefore), unixtime.Seconds(*manifestsCmdNotAfter))
+	if err != nil {
+		return err
+	}
+
+	w := output.NewWriter(*manifestsCmdOutput, os.Stdout)
+	for _, key := range keys {
+		// Loaded one at a time so that output starts promptly for hosts with long histories.
+		loaded, err := client.GetManifests(ctx, identity, manifests.ManifestKeys{key})
+		if err != nil {
+			return err
+		}
+		if err := w.Write(manifestRecord(identity, loaded[0])); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+func manifestRecord(identity manifests.NodeIdentity, m manifests.Manifest) output.Record {
+	return output.Record{
+		{Name: "cluster", Value: identity.Cluster},
+		{Name: "hostname", Value: identity.Hostname},
+		{Name: "time", Value: m.Time},
+		{Name: "type", Value: m.ManifestType.String()},
+		{Name: "files", Value: len(m.DataFiles)},
+		{Name: "data_center", Value: m.DataCenter},
+		{Name: "rack", Value: m.Rack},
+		{Name: "release_version", Value: m.ReleaseVersion},
+		{Name: "schema_version", Value: m.SchemaVersion},
+		{Name: "tool_version", Value: m.ToolVersion},
+	}
+}
+
+func Clusters(ctx context.Context) error {
+	client := bucket.OpenShared()
+	clusters, err := client.ListClusters(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := output.NewWriter(*clustersCmdOutput, os.Stdout)
+	for _, cluster := range clusters {
+		if err := w.Write(output.Record{{Name: "cluster", Value: cluster}}); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+func Hosts(ctx context.Context) error {
+	client := bucket.OpenShared()
+	identities, err := client.ListHostNames(ctx, *hostsCmdCluster)
+	if err != nil {
+		return err
+	}
+
+	w := output.NewWriter(*hostsCmdOutput, os.Stdout)
+	for _, identity := range identities {
+		record := output.Record{
+			{Name: "cluster", Value: identity.Cluster},
+			{Name: "hostname", Value: identity.Hostname},
+		}
+		if *hostsCmdDetails {
+			details, err := hostDetails(ctx, client, identity)
+			if err != nil {
+				return err
+			}
+			record = append(record, details...)
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// hostDetails summarizes a host's manifests. Fields are nil if the host has no manifests of that kind.
+func hostDetails(ctx context.Context, client *bucket.Client, identity manifests.NodeIdentity) (output.Record, error) {
+	keys, err := client.ListManifests(ctx, identity, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	var latestSnapshot, latestIncremental, latestCommitLog interface{}
+	var dataKeys manifests.ManifestKeys
+	for _, key := range keys {
+		switch key.ManifestType {
+		case manifests.ManifestTypeSnapshot:
+			latestSnapshot = key.Time
+		case manifests.ManifestTypeIncremental:
+			latestIncremental = key.Time
+		case manifests.ManifestTypeCommitLog:
+			latestCommitLog = key.Time
+			// Written without connecting to Cassandra, so they lack most node details.
+			continue
+		}
+		dataKeys = append(dataKeys, key)
+	}
+
+	var hostID, address, tokens, dataCenter, rack, releaseVersion, size interface{}
+	if len(dataKeys) > 0 {
+		newest, err := client.GetManifests(ctx, identity, dataKeys[len(dataKeys)-1:])
+		if err != nil {
+			return nil, err
+		}
+		hostID = newest[0].HostID
+		address = newest[0].Address
+		tokens = output.List(newest[0].Tokens)
+		dataCenter = newest[0].DataCenter
+		rack = newest[0].Rack
+		releaseVersion = newest[0].ReleaseVersion
+		if size, err = manifestSize(ctx, client, newest[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	return output.Record{
+		{Name: "latest_snapshot", Value: latestSnapshot},
+		{Name: "latest_incremental", Value: latestIncremental},
+		{Name: "latest_commitlog", Value: latestCommitLog},
+		{Name: "host_id", Value: hostID},
+		{Name: "address", Value: address},
+		{Name: "tokens", Value: tokens},
+		{Name: "data_center", Value: dataCenter},
+		{Name: "rack", Value: rack},
+		{Name: "release_version", Value: releaseVersion},
+		{Name: "bytes", Value: size},
+	}, nil
+}
+
+// manifestSize adds up the size of the files in a manifest. Blob sizes are used for files
+// whose length wasn't recorded by older versions.
+func manifestSize(ctx context.Context, client *bucket.Client, m manifests.Manifest) (int64, error) {
+	var total int64
+	for name, digests := range m.DataFiles {
+		if fileInfo, ok := m.DataFileInfo[name]; ok {
+			total += fileInfo.Length
+			continue
+		}
+		info, err := client.StatBlob(ctx, digests)
+		if err == bucket.NotFound {
+			continue
+		} else if err != nil {
+			return 0, err
+		}
+		total += info.ContentLength
+	}
+	return total, nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package list
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/retailnext/cassandrabackup/bucket"
+	"github.com/retailnext/cassandrabackup/manifests"
+	"github.com/retailnext/cassandrabackup/output"
+	"github.com/retailnext/cassandrabackup/unixtime"
+)
+
+func TestHostDetails(t *testing.T) {
+	ctx := context.Background()
+	client := bucket.NewClient(bucket.NewMemoryBackend(), "")
+	identity := manifests.NodeIdentity{Cluster: "test-cluster", Hostname: "test-host"}
+
+	details, err := hostDetails(ctx, client, identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range details {
+		if field.Value != nil {
+			t.Fatalf("expected nil %s for host without manifests, got %v", field.Name, field.Value)
+		}
+	}
+
+	for _, m := range []manifests.Manifest{
+		{Time: 1000, ManifestType: manifests.ManifestTypeSnapshot, HostID: "old"},
+		{Time: 2000, ManifestType: manifests.ManifestTypeIncremental, HostID: "old"},
+		{Time: 3000, ManifestType: manifests.ManifestTypeIncomplete, HostID: "new", Address: "10.0.0.1", Tokens: []string{"-1", "1"}, DataCenter: "dc1", Rack: "r1", ReleaseVersion: "3.11.4"},
+		{Time: 4000, ManifestType: manifests.ManifestTypeCommitLog, Address: "10.0.0.1"},
+	} {
+		if err := client.PutManifest(ctx, identity, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	details, err = hostDetails(ctx, client, identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := output.Record{
+		{Name: "latest_snapshot", Value: unixtime.Seconds(1000)},
+		{Name: "latest_incremental", Value: unixtime.Seconds(2000)},
+		{Name: "latest_commitlog", Value: unixtime.Seconds(4000)},
+		{Name: "host_id", Value: "new"},
+		{Name: "address", Value: "10.0.0.1"},
+		{Name: "tokens", Value: output.List{"-1", "1"}},
+		{Name: "data_center", Value: "dc1"},
+		{Name: "rack", Value: "r1"},
+		{Name: "release_version", Value: "3.11.4"},
+		{Name: "bytes", Value: int64(0)},
+	}
+	if diff := deep.Equal(details, expected); diff != nil {
+		t.Fatal(diff)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifests
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var systemKeyspaces = map[string]struct{}{
+	"system":                {},
+	"system_auth":           {},
+	"system_distributed":    {},
+	"system_schema":         {},
+	"system_traces":         {},
+	"system_views":          {},
+	"system_virtual_schema": {},
+}
+
+// IsSystemKeyspace reports whether a keyspace is created and managed by Cassandra itself.
+func IsSystemKeyspace(name string) bool {
+	_, ok := systemKeyspaces[name]
+	return ok
+}
+
+// TableFilter selects tables by include and exclude patterns. A pattern is either a glob like
+// "keyspace.table", "keyspace.*" or "keyspace" (meaning every table in it), or a regular expression
+// prefixed with "re:" that must match all of "keyspace.table".
+type TableFilter struct {
+	include []tablePattern
+	exclude []tablePattern
+	// excludeSystem rejects tables in system keyspaces even if an include pattern names them.
+	excludeSystem bool
+}
+
+type tablePattern struct {
+	spec         string
+	keyspaceGlob string
+	tableGlob    string
+	expr         *regexp.Regexp
+}
+
+// NewTableFilter matches tables that match any include pattern (or every table if there are none)
+// and no exclude pattern. The zero TableFilter matches every table.
+func NewTableFilter(include, exclude []string, includeSystem bool) (TableFilter, error) {
+	f := TableFilter{
+		excludeSystem: !includeSystem,
+	}
+	var err error
+	if f.include, err = parseTablePatterns(include); err != nil {
+		return TableFilter{}, err
+	}
+	if f.exclude, err = parseTablePatterns(exclude); err != nil {
+		return TableFilter{}, err
+	}
+	return f, nil
+}
+
+func parseTablePatterns(specs []string) ([]tablePattern, error) {
+	result := make([]tablePattern, 0, len(specs))
+	for _, spec := range specs {
+		if strings.HasPrefix(spec, "re:") {
+			expr, err := regexp.Compile("^(?:" + spec[3:] + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("invalid table pattern %q: %v", spec, err)
+			}
+			result = append(result, tablePattern{spec: spec, expr: expr})
+			continue
+		}
+
+		pattern := tablePattern{
+			spec:         spec,
+			keyspaceGlob: spec,
+			tableGlob:    "*",
+		}
+		if i := strings.Index(spec, "."); i >= 0 {
+			pattern.keyspaceGlob = spec[:i]
+			pattern.tableGlob = spec[i+1:]
+		}
+		for _, glob := range []string{pattern.keyspaceGlob, pattern.tableGlob} {
+			if _, err := path.Match(glob, ""); glob == "" || err != nil {
+				return nil, fmt.Errorf("invalid table pattern %q", spec)
+			}
+		}
+		result = append(result, pattern)
+	}
+	return result, nil
+}
+
+func (p tablePattern) match(keyspace, table string) bool {
+	if p.expr != nil {
+		return p.expr.MatchString(keyspace + "." + table)
+	}
+	keyspaceMatch, _ := path.Match(p.keyspaceGlob, keyspace)
+	tableMatch, _ := path.Match(p.tableGlob, table)
+	return keyspaceMatch && tableMatch
+}
+
+// Exclusions returns the exclude patterns the filter was built from.
+func (f TableFilter) Exclusions() []string {
+	var specs []string
+	for _, pattern := range f.exclude {
+		specs = append(specs, pattern.spec)
+	}
+	return specs
+}
+
+func (f TableFilter) Match(keyspace, table string) bool {
+	if f.excludeSystem && IsSystemKeyspace(keyspace) {
+		return false
+	}
+	if len(f.include) > 0 && !matchAny(f.include, keyspace, table) {
+		return false
+	}
+	return !matchAny(f.exclude, keyspace, table)
+}
+
+// MatchPath matches a data file path like "keyspace/table-<id>/...".
+func (f TableFilter) MatchPath(dataFile string) bool {
+	parts := strings.SplitN(dataFile, "/", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	table := parts[1]
+	if i := strings.LastIndex(table, "-"); i > 0 {
+		table = table[:i]
+	}
+	return f.Match(parts[0], table)
+}
+
+func matchAny(patterns []tablePattern, keyspace, table string) bool {
+	for _, pattern := range patterns {
+		if pattern.match(keyspace, table) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifests
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestTableFilter(t *testing.T) {
+	type check struct {
+		keyspace string
+		table    string
+		expected bool
+	}
+	cases := []struct {
+		include       []string
+		exclude       []string
+		includeSystem bool
+		checks        []check
+	}{
+		{
+			includeSystem: true,
+			checks: []check{
+				{"ks", "t", true},
+				{"system", "local", true},
+			},
+		},
+		{
+			checks: []check{
+				{"ks", "t", true},
+				{"system_schema", "tables", false},
+			},
+		},
+		{
+			include: []string{"ks", "other.t*"},
+			exclude: []string{"ks.scratch_*"},
+			checks: []check{
+				{"ks", "t", true},
+				{"ks", "scratch_1", false},
+				{"other", "things", true},
+				{"other", "stuff", false},
+				{"third", "t", false},
+			},
+		},
+		{
+			include:       []string{`re:(ks|other)\.events_[0-9]+`},
+			includeSystem: true,
+			checks: []check{
+				{"ks", "events_1", true},
+				{"other", "events_22", true},
+				{"ks", "events_x", false},
+				{"xks", "events_1", false},
+			},
+		},
+		{
+			include: []string{"system.*"},
+			checks: []check{
+				{"system", "local", false},
+			},
+		},
+	}
+	for _, c := range cases {
+		filter, err := NewTableFilter(c.include, c.exclude, c.includeSystem)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, ch := range c.checks {
+			if actual := filter.Match(ch.keyspace, ch.table); actual != ch.expected {
+				t.Errorf("include=%q exclude=%q %s.%s expected=%v actual=%v", c.include, c.exclude, ch.keyspace, ch.table, ch.expected, actual)
+			}
+		}
+	}
+
+	var zero TableFilter
+	if !zero.Match("system", "local") {
+		t.Error("zero filter should match everything")
+	}
+}
+
+func TestTableFilterMatchPath(t *testing.T) {
+	filter, err := NewTableFilter(nil, []string{"ks.scratch"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]bool{
+		"ks/scratch-bcfbb16bdd5b36ac9db83d20236eb7ee/md-1-big-Data.db":      false,
+		"ks/scratch_two-bcfbb16bdd5b36ac9db83d20236eb7ee/md-1-big-Data.db":  true,
+		"ks/scratch-bcfbb16bdd5b36ac9db83d20236eb7ee/.idx/md-1-big-Data.db": false,
+		"system/local-7ad54392bcdd35a684174e047860b377/md-1-big-Data.db":    true,
+		"not-a-data-file": false,
+	}
+	for input, expected := range cases {
+		if actual := filter.MatchPath(input); actual != expected {
+			t.Errorf("input=%q expected=%v actual=%v", input, expected, actual)
+		}
+	}
+}
+
+func TestTableFilterExclusions(t *testing.T) {
+	filter, err := NewTableFilter([]string{"ks"}, []string{"ks.scratch_*", "re:.*_tmp"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(filter.Exclusions(), []string{"ks.scratch_*", "re:.*_tmp"}); diff != nil {
+		t.Fatal(diff)
+	}
+	if (TableFilter{}).Exclusions() != nil {
+		t.Fatal("expected no exclusions")
+	}
+}
+
+func TestTableFilterInvalid(t *testing.T) {
+	for _, spec := range []string{"re:(", "ks.[", ".t", "ks."} {
+		if _, err := NewTableFilter([]string{spec}, nil, false); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
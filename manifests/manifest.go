@@ -15,7 +15,10 @@
 package manifests
 
 import (
+	"time"
+
 	"github.com/retailnext/cassandrabackup/digest"
+	"github.com/retailnext/cassandrabackup/paranoid"
 	"github.com/retailnext/cassandrabackup/unixtime"
 )
 
@@ -26,17 +29,62 @@ const (
 	ManifestTypeSnapshot    ManifestType = 1
 	ManifestTypeIncomplete  ManifestType = 2
 	ManifestTypeIncremental ManifestType = 3
+	// ManifestTypeCommitLog manifests list archived commitlog segments by file name instead of data files.
+	ManifestTypeCommitLog ManifestType = 4
 )
 
+func (t ManifestType) String() string {
+	switch t {
+	case ManifestTypeSnapshot:
+		return "snapshot"
+	case ManifestTypeIncomplete:
+		return "incomplete"
+	case ManifestTypeIncremental:
+		return "incremental"
+	case ManifestTypeCommitLog:
+		return "commitlog"
+	default:
+		return "invalid"
+	}
+}
+
 //easyjson:json
 type Manifest struct {
-	Time         unixtime.Seconds             `json:"time"`
-	ManifestType ManifestType                 `json:"manifest_type"`
-	HostID       string                       `json:"host_id"`
-	Address      string                       `json:"address"`
-	Partitioner  string                       `json:"partitioner"`
-	Tokens       []string                     `json:"tokens"`
-	DataFiles    map[string]digest.ForRestore `json:"data_files"`
+	Time         unixtime.Seconds `json:"time"`
+	ManifestType ManifestType     `json:"manifest_type"`
+	HostID       string           `json:"host_id"`
+	Address      string           `json:"address"`
+	Partitioner  string           `json:"partitioner"`
+	Tokens       []string         `json:"tokens"`
+	// These are missing from manifests written by older versions.
+	DataCenter     string `json:"data_center,omitempty"`
+	Rack           string `json:"rack,omitempty"`
+	ReleaseVersion string `json:"release_version,omitempty"`
+	SchemaVersion  string `json:"schema_version,omitempty"`
+	ToolVersion    string `json:"tool_version,omitempty"`
+	// Schema is the ID of the CQL schema document uploaded with a snapshot, if any.
+	Schema string `json:"schema,omitempty"`
+	// ExcludedTables lists the patterns of tables deliberately left out of this backup.
+	ExcludedTables []string                     `json:"excluded_tables,omitempty"`
+	DataFiles      map[string]digest.ForRestore `json:"data_files"`
+	// DataFileInfo is keyed like DataFiles. It is missing from manifests written by older versions.
+	DataFileInfo map[string]FileInfo `json:"data_file_info,omitempty"`
+}
+
+type FileInfo struct {
+	Length       int64 `json:"length"`
+	ModTimeNanos int64 `json:"mtime_ns"`
+}
+
+func NewFileInfo(file paranoid.File) FileInfo {
+	return FileInfo{
+		Length:       file.Len(),
+		ModTimeNanos: file.ModTime().UnixNano(),
+	}
+}
+
+func (i FileInfo) ModTime() time.Time {
+	return time.Unix(0, i.ModTimeNanos)
 }
 
 func (m Manifest) Key() ManifestKey {
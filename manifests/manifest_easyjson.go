@@ -4,7 +4,6 @@ package manifests
 
 import (
 	json "encoding/json"
-
 	easyjson "github.com/mailru/easyjson"
 	jlexer "github.com/mailru/easyjson/jlexer"
 	jwriter "github.com/mailru/easyjson/jwriter"
@@ -19,7 +18,7 @@ var (
 	_ easyjson.Marshaler
 )
 
-func easyjson4ef6ea8bDecodeCassandrabackupManifests(in *jlexer.Lexer, out *Manifest) {
+func easyjson4ef6ea8bDecodeGithubComRetailnextCassandrabackupManifests(in *jlexer.Lexer, out *Manifest) {
 	isTopLevel := in.IsStart()
 	if in.IsNull() {
 		if isTopLevel {
@@ -71,6 +70,41 @@ func easyjson4ef6ea8bDecodeCassandrabackupManifests(in *jlexer.Lexer, out *Manif
 				}
 				in.Delim(']')
 			}
+		case "data_center":
+			out.DataCenter = string(in.String())
+		case "rack":
+			out.Rack = string(in.String())
+		case "release_version":
+			out.ReleaseVersion = string(in.String())
+		case "schema_version":
+			out.SchemaVersion = string(in.String())
+		case "tool_version":
+			out.ToolVersion = string(in.String())
+		case "schema":
+			out.Schema = string(in.String())
+		case "excluded_tables":
+			if in.IsNull() {
+				in.Skip()
+				out.ExcludedTables = nil
+			} else {
+				in.Delim('[')
+				if out.ExcludedTables == nil {
+					if !in.IsDelim(']') {
+						out.ExcludedTables = make([]string, 0, 4)
+					} else {
+						out.ExcludedTables = []string{}
+					}
+				} else {
+					out.ExcludedTables = (out.ExcludedTables)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v2 string
+					v2 = string(in.String())
+					out.ExcludedTables = append(out.ExcludedTables, v2)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
 		case "data_files":
 			if in.IsNull() {
 				in.Skip()
@@ -84,9 +118,29 @@ func easyjson4ef6ea8bDecodeCassandrabackupManifests(in *jlexer.Lexer, out *Manif
 				for !in.IsDelim('}') {
 					key := string(in.String())
 					in.WantColon()
-					var v2 digest.ForRestore
-					(v2).UnmarshalEasyJSON(in)
-					(out.DataFiles)[key] = v2
+					var v3 digest.ForRestore
+					(v3).UnmarshalEasyJSON(in)
+					(out.DataFiles)[key] = v3
+					in.WantComma()
+				}
+				in.Delim('}')
+			}
+		case "data_file_info":
+			if in.IsNull() {
+				in.Skip()
+			} else {
+				in.Delim('{')
+				if !in.IsDelim('}') {
+					out.DataFileInfo = make(map[string]FileInfo)
+				} else {
+					out.DataFileInfo = nil
+				}
+				for !in.IsDelim('}') {
+					key := string(in.String())
+					in.WantColon()
+					var v4 FileInfo
+					(v4).UnmarshalEasyJSON(in)
+					(out.DataFileInfo)[key] = v4
 					in.WantComma()
 				}
 				in.Delim('}')
@@ -101,103 +155,131 @@ func easyjson4ef6ea8bDecodeCassandrabackupManifests(in *jlexer.Lexer, out *Manif
 		in.Consumed()
 	}
 }
-func easyjson4ef6ea8bEncodeCassandrabackupManifests(out *jwriter.Writer, in Manifest) {
+func easyjson4ef6ea8bEncodeGithubComRetailnextCassandrabackupManifests(out *jwriter.Writer, in Manifest) {
 	out.RawByte('{')
 	first := true
 	_ = first
 	{
 		const prefix string = ",\"time\":"
-		if first {
-			first = false
-			out.RawString(prefix[1:])
-		} else {
-			out.RawString(prefix)
-		}
+		out.RawString(prefix[1:])
 		(in.Time).MarshalEasyJSON(out)
 	}
 	{
 		const prefix string = ",\"manifest_type\":"
-		if first {
-			first = false
-			out.RawString(prefix[1:])
-		} else {
-			out.RawString(prefix)
-		}
+		out.RawString(prefix)
 		out.Int(int(in.ManifestType))
 	}
 	{
 		const prefix string = ",\"host_id\":"
-		if first {
-			first = false
-			out.RawString(prefix[1:])
-		} else {
-			out.RawString(prefix)
-		}
+		out.RawString(prefix)
 		out.String(string(in.HostID))
 	}
 	{
 		const prefix string = ",\"address\":"
-		if first {
-			first = false
-			out.RawString(prefix[1:])
-		} else {
-			out.RawString(prefix)
-		}
+		out.RawString(prefix)
 		out.String(string(in.Address))
 	}
 	{
 		const prefix string = ",\"partitioner\":"
-		if first {
-			first = false
-			out.RawString(prefix[1:])
-		} else {
-			out.RawString(prefix)
-		}
+		out.RawString(prefix)
 		out.String(string(in.Partitioner))
 	}
 	{
 		const prefix string = ",\"tokens\":"
-		if first {
-			first = false
-			out.RawString(prefix[1:])
-		} else {
-			out.RawString(prefix)
-		}
+		out.RawString(prefix)
 		if in.Tokens == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
 			out.RawString("null")
 		} else {
 			out.RawByte('[')
-			for v3, v4 := range in.Tokens {
-				if v3 > 0 {
+			for v5, v6 := range in.Tokens {
+				if v5 > 0 {
 					out.RawByte(',')
 				}
-				out.String(string(v4))
+				out.String(string(v6))
+			}
+			out.RawByte(']')
+		}
+	}
+	if in.DataCenter != "" {
+		const prefix string = ",\"data_center\":"
+		out.RawString(prefix)
+		out.String(string(in.DataCenter))
+	}
+	if in.Rack != "" {
+		const prefix string = ",\"rack\":"
+		out.RawString(prefix)
+		out.String(string(in.Rack))
+	}
+	if in.ReleaseVersion != "" {
+		const prefix string = ",\"release_version\":"
+		out.RawString(prefix)
+		out.String(string(in.ReleaseVersion))
+	}
+	if in.SchemaVersion != "" {
+		const prefix string = ",\"schema_version\":"
+		out.RawString(prefix)
+		out.String(string(in.SchemaVersion))
+	}
+	if in.ToolVersion != "" {
+		const prefix string = ",\"tool_version\":"
+		out.RawString(prefix)
+		out.String(string(in.ToolVersion))
+	}
+	if in.Schema != "" {
+		const prefix string = ",\"schema\":"
+		out.RawString(prefix)
+		out.String(string(in.Schema))
+	}
+	if len(in.ExcludedTables) != 0 {
+		const prefix string = ",\"excluded_tables\":"
+		out.RawString(prefix)
+		{
+			out.RawByte('[')
+			for v7, v8 := range in.ExcludedTables {
+				if v7 > 0 {
+					out.RawByte(',')
+				}
+				out.String(string(v8))
 			}
 			out.RawByte(']')
 		}
 	}
 	{
 		const prefix string = ",\"data_files\":"
-		if first {
-			first = false
-			out.RawString(prefix[1:])
-		} else {
-			out.RawString(prefix)
-		}
+		out.RawString(prefix)
 		if in.DataFiles == nil && (out.Flags&jwriter.NilMapAsEmpty) == 0 {
 			out.RawString(`null`)
 		} else {
 			out.RawByte('{')
-			v5First := true
-			for v5Name, v5Value := range in.DataFiles {
-				if v5First {
-					v5First = false
+			v9First := true
+			for v9Name, v9Value := range in.DataFiles {
+				if v9First {
+					v9First = false
+				} else {
+					out.RawByte(',')
+				}
+				out.String(string(v9Name))
+				out.RawByte(':')
+				(v9Value).MarshalEasyJSON(out)
+			}
+			out.RawByte('}')
+		}
+	}
+	if len(in.DataFileInfo) != 0 {
+		const prefix string = ",\"data_file_info\":"
+		out.RawString(prefix)
+		{
+			out.RawByte('{')
+			v10First := true
+			for v10Name, v10Value := range in.DataFileInfo {
+				if v10First {
+					v10First = false
 				} else {
 					out.RawByte(',')
 				}
-				out.String(string(v5Name))
+				out.String(string(v10Name))
 				out.RawByte(':')
-				(v5Value).MarshalEasyJSON(out)
+				(v10Value).MarshalEasyJSON(out)
 			}
 			out.RawByte('}')
 		}
@@ -208,23 +290,96 @@ func easyjson4ef6ea8bEncodeCassandrabackupManifests(out *jwriter.Writer, in Mani
 // MarshalJSON supports json.Marshaler interface
 func (v Manifest) MarshalJSON() ([]byte, error) {
 	w := jwriter.Writer{}
-	easyjson4ef6ea8bEncodeCassandrabackupManifests(&w, v)
+	easyjson4ef6ea8bEncodeGithubComRetailnextCassandrabackupManifests(&w, v)
 	return w.Buffer.BuildBytes(), w.Error
 }
 
 // MarshalEasyJSON supports easyjson.Marshaler interface
 func (v Manifest) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjson4ef6ea8bEncodeCassandrabackupManifests(w, v)
+	easyjson4ef6ea8bEncodeGithubComRetailnextCassandrabackupManifests(w, v)
 }
 
 // UnmarshalJSON supports json.Unmarshaler interface
 func (v *Manifest) UnmarshalJSON(data []byte) error {
 	r := jlexer.Lexer{Data: data}
-	easyjson4ef6ea8bDecodeCassandrabackupManifests(&r, v)
+	easyjson4ef6ea8bDecodeGithubComRetailnextCassandrabackupManifests(&r, v)
 	return r.Error()
 }
 
 // UnmarshalEasyJSON supports easyjson.Unmarshaler interface
 func (v *Manifest) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjson4ef6ea8bDecodeCassandrabackupManifests(l, v)
+	easyjson4ef6ea8bDecodeGithubComRetailnextCassandrabackupManifests(l, v)
+}
+func easyjson4ef6ea8bDecodeGithubComRetailnextCassandrabackupManifests1(in *jlexer.Lexer, out *FileInfo) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "length":
+			out.Length = int64(in.Int64())
+		case "mtime_ns":
+			out.ModTimeNanos = int64(in.Int64())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson4ef6ea8bEncodeGithubComRetailnextCassandrabackupManifests1(out *jwriter.Writer, in FileInfo) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"length\":"
+		out.RawString(prefix[1:])
+		out.Int64(int64(in.Length))
+	}
+	{
+		const prefix string = ",\"mtime_ns\":"
+		out.RawString(prefix)
+		out.Int64(int64(in.ModTimeNanos))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v FileInfo) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson4ef6ea8bEncodeGithubComRetailnextCassandrabackupManifests1(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v FileInfo) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson4ef6ea8bEncodeGithubComRetailnextCassandrabackupManifests1(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *FileInfo) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson4ef6ea8bDecodeGithubComRetailnextCassandrabackupManifests1(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *FileInfo) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson4ef6ea8bDecodeGithubComRetailnextCassandrabackupManifests1(l, v)
 }
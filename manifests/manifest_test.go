@@ -19,6 +19,7 @@ import (
 	"crypto/rand"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/go-test/deep"
@@ -69,6 +70,9 @@ func TestManifest(t *testing.T) {
 		DataFiles: map[string]digest.ForRestore{
 			tempFileName: dgst.ForRestore(),
 		},
+		DataFileInfo: map[string]FileInfo{
+			tempFileName: NewFileInfo(parFile),
+		},
 	}
 
 	jsonBytes, err := easyjson.Marshal(m1)
@@ -86,3 +90,17 @@ func TestManifest(t *testing.T) {
 		t.Fatal(diff)
 	}
 }
+
+func TestManifestWithoutFileInfo(t *testing.T) {
+	// As written before file info was recorded.
+	jsonBytes := []byte(`{"time":"2019-01-01T00:00:00Z","manifest_type":1,"host_id":"foobar","address":"","partitioner":"","tokens":null,` +
+		`"data_files":{"ks/t-1/md-1-big-Data.db":"` + strings.Repeat("A", 86) + `=="}}`)
+
+	var m Manifest
+	if err := easyjson.Unmarshal(jsonBytes, &m); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.DataFiles) != 1 || m.DataFileInfo != nil {
+		t.Fatalf("unexpected manifest %+v", m)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifests
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/retailnext/cassandrabackup/digest"
+)
+
+// TableName turns a data file path like "keyspace/table-<id>/..." into "keyspace.table".
+// Secondary index files are attributed to their table.
+func TableName(dataFile string) string {
+	parts := strings.SplitN(dataFile, "/", 3)
+	if len(parts) < 3 {
+		return dataFile
+	}
+	table := parts[1]
+	if i := strings.LastIndex(table, "-"); i > 0 {
+		table = table[:i]
+	}
+	return parts[0] + "." + table
+}
+
+const (
+	FileAdded   = "added"
+	FileRemoved = "removed"
+	FileChanged = "changed"
+)
+
+// FileChange describes how one data file differs between two sets of data files.
+// Before is unset for added files, and After for removed ones.
+type FileChange struct {
+	Table  string
+	Path   string
+	Change string
+	Before digest.ForRestore
+	After  digest.ForRestore
+}
+
+// DiffDataFiles returns the changes from before to after, sorted by table and path.
+func DiffDataFiles(before, after map[string]digest.ForRestore) []FileChange {
+	var changes []FileChange
+	for name, beforeDigest := range before {
+		afterDigest, ok := after[name]
+		switch {
+		case !ok:
+			changes = append(changes, FileChange{Path: name, Change: FileRemoved, Before: beforeDigest})
+		case afterDigest != beforeDigest:
+			changes = append(changes, FileChange{Path: name, Change: FileChanged, Before: beforeDigest, After: afterDigest})
+		}
+	}
+	for name, afterDigest := range after {
+		if _, ok := before[name]; !ok {
+			changes = append(changes, FileChange{Path: name, Change: FileAdded, After: afterDigest})
+		}
+	}
+	for i := range changes {
+		changes[i].Table = TableName(changes[i].Path)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Table != changes[j].Table {
+			return changes[i].Table < changes[j].Table
+		}
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
@@ -0,0 +1,74 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifests
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/retailnext/cassandrabackup/digest"
+)
+
+func testDigest(t *testing.T, b byte) digest.ForRestore {
+	data := make([]byte, 64)
+	data[0] = b
+	var result digest.ForRestore
+	if err := result.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestTableName(t *testing.T) {
+	cases := map[string]string{
+		"luneta/site-bcfbb16bdd5b36ac9db83d20236eb7ee/md-462-big-Data.db":                             "luneta.site",
+		"luneta/site-bcfbb16bdd5b36ac9db83d20236eb7ee/.site_subscription_uuid_index/md-1-big-Data.db": "luneta.site",
+		"system_schema/indexes-0feb57ac311f382fba6d9024d305702f/md-1-big-Data.db":                     "system_schema.indexes",
+		"ks/legacy_table/ks-legacy_table-ka-1-Data.db":                                                "ks.legacy_table",
+		"unexpected": "unexpected",
+	}
+	for input, expected := range cases {
+		if actual := TableName(input); actual != expected {
+			t.Fatalf("input=%q expected=%q actual=%q", input, expected, actual)
+		}
+	}
+}
+
+func TestDiffDataFiles(t *testing.T) {
+	a := testDigest(t, 1)
+	b := testDigest(t, 2)
+	before := map[string]digest.ForRestore{
+		"ks/t1-0123/md-1-big-Data.db":         a,
+		"ks/t1-0123/md-2-big-Data.db":         a,
+		"ks/t1-0123/.t1_idx/md-1-big-Data.db": a,
+		"ks/t2-4567/md-1-big-Data.db":         a,
+	}
+	after := map[string]digest.ForRestore{
+		"ks/t1-0123/md-1-big-Data.db":         b,
+		"ks/t1-0123/.t1_idx/md-1-big-Data.db": a,
+		"ks/t2-4567/md-1-big-Data.db":         a,
+		"ks/t2-4567/md-2-big-Data.db":         b,
+	}
+
+	expected := []FileChange{
+		{Table: "ks.t1", Path: "ks/t1-0123/md-1-big-Data.db", Change: FileChanged, Before: a, After: b},
+		{Table: "ks.t1", Path: "ks/t1-0123/md-2-big-Data.db", Change: FileRemoved, Before: a},
+		{Table: "ks.t2", Path: "ks/t2-4567/md-2-big-Data.db", Change: FileAdded, After: b},
+	}
+	deep.CompareUnexportedFields = true
+	if diff := deep.Equal(DiffDataFiles(before, after), expected); diff != nil {
+		t.Fatal(diff)
+	}
+}
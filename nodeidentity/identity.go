@@ -21,6 +21,7 @@ import (
 	"github.com/retailnext/cassandrabackup/manifests"
 	"github.com/retailnext/cassandrabackup/systemlocal"
 	"github.com/retailnext/cassandrabackup/unixtime"
+	"github.com/retailnext/cassandrabackup/version"
 	"go.uber.org/zap"
 )
 
@@ -68,6 +69,7 @@ func GetIdentityAndManifestTemplateOffline(overrideCluster, overrideHostname *st
 		Address:     cfg.IPForClients(),
 		Partitioner: cfg.Partitioner,
 		Tokens:      cfg.Tokens(),
+		ToolVersion: version.Version,
 	}
 	return identity, template, nil
 }
@@ -92,6 +94,10 @@ func GetIdentityAndManifestTemplate(overrideCluster, overrideHostname *string) (
 	}
 
 	template.HostID = info.HostID
+	template.DataCenter = info.DataCenter
+	template.Rack = info.Rack
+	template.ReleaseVersion = info.ReleaseVersion
+	template.SchemaVersion = info.SchemaVersion
 	if len(template.Tokens) == 0 {
 		template.Tokens = info.Tokens
 	} else {
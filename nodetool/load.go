@@ -0,0 +1,47 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodetool
+
+import (
+	"os/exec"
+
+	"go.uber.org/zap"
+)
+
+// Refresh loads sstables that have been placed in a table's data directory (Cassandra 3.x).
+func Refresh(keyspace, table string) error {
+	lgr := zap.S()
+	cmd := exec.Command(Tool, "-h", "localhost", "refresh", "--", keyspace, table)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		lgr.Errorw("refresh_fail", "keyspace", keyspace, "table", table, "err", err, "output", output)
+		return err
+	}
+	lgr.Infow("refreshed_table", "keyspace", keyspace, "table", table)
+	return nil
+}
+
+// Import moves sstables from directory into a table (Cassandra 4.0 and later).
+func Import(keyspace, table, directory string) error {
+	lgr := zap.S()
+	cmd := exec.Command(Tool, "-h", "localhost", "import", "--", keyspace, table, directory)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		lgr.Errorw("import_fail", "keyspace", keyspace, "table", table, "directory", directory, "err", err, "output", output)
+		return err
+	}
+	lgr.Infow("imported_table", "keyspace", keyspace, "table", table, "directory", directory)
+	return nil
+}
@@ -0,0 +1,172 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package output writes command results to stdout as a table, JSON, JSON lines or CSV, separately from logs.
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+)
+
+// Flag adds the standard --output flag to cmd.
+func Flag(cmd *kingpin.CmdClause) *string {
+	return cmd.Flag("output", "Output format: table, json, jsonl or csv.").Default(FormatTable).Enum(FormatTable, FormatJSON, FormatJSONL, FormatCSV)
+}
+
+// Field is a named value in a Record. Table and CSV output use fmt.Sprint(Value), or nothing for nil, while
+// JSON output marshals Value as is.
+type Field struct {
+	Name  string
+	Value interface{}
+}
+
+// Record is one row of output. Every record written to a Writer should have the same fields.
+type Record []Field
+
+func (r Record) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range r {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(field.Name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(field.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (r Record) names() []string {
+	result := make([]string, len(r))
+	for i, field := range r {
+		result[i] = field.Name
+	}
+	return result
+}
+
+func (r Record) strings() []string {
+	result := make([]string, len(r))
+	for i, field := range r {
+		if field.Value != nil {
+			result[i] = fmt.Sprint(field.Value)
+		}
+	}
+	return result
+}
+
+// List is output as a JSON array, or comma separated in tables and CSV.
+type List []string
+
+func (l List) String() string {
+	return strings.Join(l, ",")
+}
+
+type Writer struct {
+	format string
+	w      io.Writer
+
+	buffered []Record
+	csv      *csv.Writer
+}
+
+func NewWriter(format string, w io.Writer) *Writer {
+	return &Writer{
+		format: format,
+		w:      w,
+	}
+}
+
+// Write outputs record, or buffers it until Flush for formats that need to see every record.
+func (w *Writer) Write(record Record) error {
+	switch w.format {
+	case FormatJSONL:
+		line, err := record.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w.w, "%s\n", line)
+		return err
+	case FormatCSV:
+		if w.csv == nil {
+			w.csv = csv.NewWriter(w.w)
+			if err := w.csv.Write(record.names()); err != nil {
+				return err
+			}
+		}
+		return w.csv.Write(record.strings())
+	default:
+		w.buffered = append(w.buffered, record)
+		return nil
+	}
+}
+
+func (w *Writer) Flush() error {
+	switch w.format {
+	case FormatJSONL:
+		return nil
+	case FormatCSV:
+		if w.csv == nil {
+			return nil
+		}
+		w.csv.Flush()
+		return w.csv.Error()
+	case FormatJSON:
+		records := w.buffered
+		if records == nil {
+			records = []Record{}
+		}
+		encoder := json.NewEncoder(w.w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+	default:
+		if len(w.buffered) == 0 {
+			return nil
+		}
+		tw := tabwriter.NewWriter(w.w, 0, 8, 2, ' ', 0)
+		if _, err := fmt.Fprintln(tw, strings.ToUpper(strings.Join(w.buffered[0].names(), "\t"))); err != nil {
+			return err
+		}
+		for _, record := range w.buffered {
+			if _, err := fmt.Fprintln(tw, strings.Join(record.strings(), "\t")); err != nil {
+				return err
+			}
+		}
+		return tw.Flush()
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/retailnext/cassandrabackup/unixtime"
+)
+
+func TestWriter(t *testing.T) {
+	records := []Record{
+		{{"hostname", "a"}, {"time", unixtime.Seconds(0)}, {"files", 3}},
+		{{"hostname", "b,c"}, {"time", unixtime.Seconds(60)}, {"files", 10}},
+	}
+	cases := map[string]string{
+		FormatTable: "HOSTNAME  TIME                  FILES\n" +
+			"a         1970-01-01T00:00:00Z  3\n" +
+			"b,c       1970-01-01T00:01:00Z  10\n",
+		FormatCSV: "hostname,time,files\n" +
+			"a,1970-01-01T00:00:00Z,3\n" +
+			"\"b,c\",1970-01-01T00:01:00Z,10\n",
+		FormatJSONL: "{\"hostname\":\"a\",\"time\":0,\"files\":3}\n" +
+			"{\"hostname\":\"b,c\",\"time\":60,\"files\":10}\n",
+		FormatJSON: "[\n" +
+			"  {\n    \"hostname\": \"a\",\n    \"time\": 0,\n    \"files\": 3\n  },\n" +
+			"  {\n    \"hostname\": \"b,c\",\n    \"time\": 60,\n    \"files\": 10\n  }\n" +
+			"]\n",
+	}
+	for format, expected := range cases {
+		var buf bytes.Buffer
+		w := NewWriter(format, &buf)
+		for _, record := range records {
+			if err := w.Write(record); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != expected {
+			t.Fatalf("format=%s expected:\n%s\nactual:\n%s", format, expected, buf.String())
+		}
+	}
+}
+
+func TestWriterNil(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(FormatCSV, &buf)
+	if err := w.Write(Record{{"a", nil}, {"b", 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "a,b\n,1\n" {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+}
+
+func TestWriterEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(FormatJSON, &buf)
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "[]\n" {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prune
+
+import "gopkg.in/alecthomas/kingpin.v2"
+
+var (
+	Cmd = kingpin.Command("prune", "Delete expired manifests and the blobs no remaining manifest references, along with any of their noncurrent versions that are no longer locked.")
+
+	cmdClusters    = Cmd.Flag("cluster", "Only apply the retention policy to manifests in these clusters.").Strings()
+	cmdKeepLast    = Cmd.Flag("keep-last", "Keep this many of the newest snapshots.").Int()
+	cmdKeepDaily   = Cmd.Flag("keep-daily", "Keep the newest snapshot of each of this many days.").Int()
+	cmdKeepWeekly  = Cmd.Flag("keep-weekly", "Keep the newest snapshot of each of this many weeks.").Int()
+	cmdKeepMonthly = Cmd.Flag("keep-monthly", "Keep the newest snapshot of each of this many months.").Int()
+	cmdGracePeriod = Cmd.Flag("grace-period", "Never delete manifests or blobs newer than this, or blobs first found unreferenced more recently than this, to protect backups in progress.").Default("24h").Duration()
+	cmdDryRun      = Cmd.Flag("dry-run", "Don't actually delete anything").Bool()
+	cmdIKnow       = Cmd.Flag("i-know", "Delete unreferenced blobs even without object lock. A backup running at the same time may still be relying on them.").Bool()
+)
@@ -0,0 +1,65 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prune
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	prunedManifests = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "cassandrabackup",
+		Subsystem: "prune",
+		Name:      "deleted_manifests_total",
+		Help:      "Number of expired manifests deleted.",
+	})
+	prunedBlobs = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "cassandrabackup",
+		Subsystem: "prune",
+		Name:      "deleted_blobs_total",
+		Help:      "Number of unreferenced blobs deleted.",
+	})
+	prunedBytes = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "cassandrabackup",
+		Subsystem: "prune",
+		Name:      "deleted_bytes_total",
+		Help:      "Total size of unreferenced blobs deleted.",
+	})
+	prunedSchemas = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "cassandrabackup",
+		Subsystem: "prune",
+		Name:      "deleted_schemas_total",
+		Help:      "Number of unreferenced schemas deleted.",
+	})
+	purgedVersions = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "cassandrabackup",
+		Subsystem: "prune",
+		Name:      "purged_versions_total",
+		Help:      "Number of noncurrent object versions permanently deleted.",
+	})
+	purgedBytes = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "cassandrabackup",
+		Subsystem: "prune",
+		Name:      "purged_bytes_total",
+		Help:      "Total size of noncurrent object versions permanently deleted.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(prunedManifests)
+	prometheus.MustRegister(prunedBlobs)
+	prometheus.MustRegister(prunedBytes)
+	prometheus.MustRegister(prunedSchemas)
+	prometheus.MustRegister(purgedVersions)
+	prometheus.MustRegister(purgedBytes)
+}
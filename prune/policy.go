@@ -0,0 +1,108 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prune
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/retailnext/cassandrabackup/manifests"
+	"github.com/retailnext/cassandrabackup/unixtime"
+)
+
+// Policy selects which snapshots of a host to keep. The newest snapshot is always kept, as is every
+// other manifest from the time of the oldest kept snapshot onwards.
+type Policy struct {
+	KeepLast    int
+	KeepDaily   int
+	KeepWeekly  int
+	KeepMonthly int
+}
+
+func (p Policy) IsEmpty() bool {
+	return p.KeepLast <= 0 && p.KeepDaily <= 0 && p.KeepWeekly <= 0 && p.KeepMonthly <= 0
+}
+
+// Apply splits the manifests of a single host into those to keep and those to remove.
+// Manifests after protectAfter are always kept.
+func (p Policy) Apply(keys manifests.ManifestKeys, protectAfter unixtime.Seconds) (keep, remove manifests.ManifestKeys) {
+	sorted := make(manifests.ManifestKeys, len(keys))
+	copy(sorted, keys)
+	sort.Sort(sorted)
+
+	var snapshots []manifests.ManifestKey
+	for i := len(sorted) - 1; i >= 0; i-- {
+		if sorted[i].ManifestType == manifests.ManifestTypeSnapshot {
+			snapshots = append(snapshots, sorted[i])
+		}
+	}
+	if len(snapshots) == 0 {
+		// Nothing could be restored from what would be left.
+		return sorted, nil
+	}
+
+	kept := make(map[manifests.ManifestKey]struct{})
+	kept[snapshots[0]] = struct{}{}
+	for i := 0; i < p.KeepLast && i < len(snapshots); i++ {
+		kept[snapshots[i]] = struct{}{}
+	}
+	keepNewestPerPeriod(kept, snapshots, p.KeepDaily, func(t time.Time) string {
+		return t.Format("2006-01-02")
+	})
+	keepNewestPerPeriod(kept, snapshots, p.KeepWeekly, func(t time.Time) string {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-%02d", year, week)
+	})
+	keepNewestPerPeriod(kept, snapshots, p.KeepMonthly, func(t time.Time) string {
+		return t.Format("2006-01")
+	})
+
+	oldestKept := snapshots[0].Time
+	for key := range kept {
+		if key.Time < oldestKept {
+			oldestKept = key.Time
+		}
+	}
+
+	for _, key := range sorted {
+		_, isKept := kept[key]
+		if key.ManifestType != manifests.ManifestTypeSnapshot {
+			isKept = key.Time >= oldestKept
+		}
+		if isKept || key.Time > protectAfter {
+			keep = append(keep, key)
+		} else {
+			remove = append(remove, key)
+		}
+	}
+	return keep, remove
+}
+
+// keepNewestPerPeriod keeps the newest of snapshots (ordered newest first) in each of the newest count periods.
+func keepNewestPerPeriod(kept map[manifests.ManifestKey]struct{}, snapshots []manifests.ManifestKey, count int, period func(time.Time) string) {
+	seen := make(map[string]struct{})
+	for _, snapshot := range snapshots {
+		if len(seen) >= count {
+			return
+		}
+		name := period(time.Unix(int64(snapshot.Time), 0).UTC())
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		kept[snapshot] = struct{}{}
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prune
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/retailnext/cassandrabackup/manifests"
+	"github.com/retailnext/cassandrabackup/unixtime"
+)
+
+const day = 86400
+
+func snapshot(t unixtime.Seconds) manifests.ManifestKey {
+	return manifests.ManifestKey{Time: t, ManifestType: manifests.ManifestTypeSnapshot}
+}
+
+func incremental(t unixtime.Seconds) manifests.ManifestKey {
+	return manifests.ManifestKey{Time: t, ManifestType: manifests.ManifestTypeIncremental}
+}
+
+func TestPolicyKeepLast(t *testing.T) {
+	keys := manifests.ManifestKeys{
+		snapshot(1 * day), incremental(1*day + 100),
+		snapshot(2 * day), incremental(2*day + 100),
+		snapshot(3 * day), incremental(3*day + 100),
+	}
+	keep, remove := Policy{KeepLast: 2}.Apply(keys, 10*day)
+	if diff := deep.Equal(keep, keys[2:]); diff != nil {
+		t.Fatal(diff)
+	}
+	if diff := deep.Equal(remove, keys[:2]); diff != nil {
+		t.Fatal(diff)
+	}
+}
+
+func TestPolicyKeepDaily(t *testing.T) {
+	keys := manifests.ManifestKeys{
+		snapshot(1 * day), snapshot(1*day + 100),
+		snapshot(2 * day), snapshot(2*day + 100),
+		snapshot(3 * day), snapshot(3*day + 100),
+	}
+	keep, remove := Policy{KeepDaily: 2}.Apply(keys, 10*day)
+	if diff := deep.Equal(keep, manifests.ManifestKeys{keys[3], keys[5]}); diff != nil {
+		t.Fatal(diff)
+	}
+	if diff := deep.Equal(remove, manifests.ManifestKeys{keys[0], keys[1], keys[2], keys[4]}); diff != nil {
+		t.Fatal(diff)
+	}
+}
+
+func TestPolicyKeepsIncrementalsAfterOldestKeptSnapshot(t *testing.T) {
+	// 1970-01-01 was a Thursday, so days 4 and 11 start new ISO weeks.
+	keys := manifests.ManifestKeys{
+		snapshot(1 * day), incremental(2 * day),
+		snapshot(5 * day), incremental(6 * day),
+		snapshot(8 * day), incremental(9 * day),
+		snapshot(12 * day), incremental(13 * day),
+	}
+	keep, remove := Policy{KeepLast: 1, KeepWeekly: 2}.Apply(keys, 20*day)
+	if diff := deep.Equal(keep, keys[4:]); diff != nil {
+		t.Fatal(diff)
+	}
+	if diff := deep.Equal(remove, keys[:4]); diff != nil {
+		t.Fatal(diff)
+	}
+}
+
+func TestPolicyGracePeriod(t *testing.T) {
+	keys := manifests.ManifestKeys{
+		snapshot(1 * day), snapshot(2 * day), snapshot(3 * day),
+	}
+	keep, remove := Policy{KeepLast: 1}.Apply(keys, 2*day-1)
+	if diff := deep.Equal(keep, keys[1:]); diff != nil {
+		t.Fatal(diff)
+	}
+	if diff := deep.Equal(remove, keys[:1]); diff != nil {
+		t.Fatal(diff)
+	}
+}
+
+func TestPolicyWithoutSnapshots(t *testing.T) {
+	keys := manifests.ManifestKeys{
+		incremental(1 * day), incremental(2 * day),
+	}
+	keep, remove := Policy{KeepLast: 1}.Apply(keys, 10*day)
+	if diff := deep.Equal(keep, keys); diff != nil {
+		t.Fatal(diff)
+	}
+	if len(remove) != 0 {
+		t.Fatalf("expected nothing removed, got %v", remove)
+	}
+}
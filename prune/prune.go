@@ -0,0 +1,350 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prune
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/retailnext/cassandrabackup/bucket"
+	"github.com/retailnext/cassandrabackup/digest"
+	"github.com/retailnext/cassandrabackup/manifests"
+	"github.com/retailnext/cassandrabackup/unixtime"
+	"go.uber.org/zap"
+)
+
+var NoRetentionPolicy = errors.New("at least one of --keep-last, --keep-daily, --keep-weekly or --keep-monthly is required")
+var ObjectLockRequired = errors.New("refusing to delete blobs without object lock, since a concurrent backup may be reusing them; pass --i-know to do it anyway")
+
+func Main(ctx context.Context) error {
+	p := pruner{
+		client: bucket.OpenShared(),
+		policy: Policy{
+			KeepLast:    *cmdKeepLast,
+			KeepDaily:   *cmdKeepDaily,
+			KeepWeekly:  *cmdKeepWeekly,
+			KeepMonthly: *cmdKeepMonthly,
+		},
+		clusters:    *cmdClusters,
+		gracePeriod: *cmdGracePeriod,
+		dryRun:      *cmdDryRun,
+		iKnow:       *cmdIKnow,
+	}
+	if p.policy.IsEmpty() {
+		return NoRetentionPolicy
+	}
+	return p.run(ctx)
+}
+
+type pruner struct {
+	client      *bucket.Client
+	policy      Policy
+	clusters    []string
+	gracePeriod time.Duration
+	dryRun      bool
+	// iKnow allows deleting blobs even though they aren't protected by object lock.
+	iKnow bool
+}
+
+type expiredManifests struct {
+	identity manifests.NodeIdentity
+	keys     manifests.ManifestKeys
+}
+
+// references are the blobs and schemas used by surviving manifests.
+type references struct {
+	blobs   map[digest.ForRestore]struct{}
+	schemas map[string]struct{}
+}
+
+func (r references) add(m manifests.Manifest) {
+	for _, digests := range m.DataFiles {
+		r.blobs[digests] = struct{}{}
+	}
+	if m.Schema != "" {
+		r.schemas[m.Schema] = struct{}{}
+	}
+}
+
+func (p *pruner) run(ctx context.Context) error {
+	lgr := zap.S()
+	if !p.dryRun && !p.iKnow && !p.client.ObjectLockEnabled() {
+		return ObjectLockRequired
+	}
+	markedAt := time.Now()
+	protectAfter := markedAt.Add(-p.gracePeriod)
+
+	// Mark every blob referenced by a surviving manifest in any cluster, since blobs are shared
+	// between clusters. Nothing is deleted until marking has completed without error.
+	expired, referenced, err := p.mark(ctx, unixtime.Seconds(protectAfter.Unix()))
+	if err != nil {
+		return err
+	}
+
+	var manifestCount int
+	for _, e := range expired {
+		for _, key := range e.keys {
+			manifestCount++
+			if p.dryRun {
+				lgr.Infow("prune_would_delete_manifest", "identity", e.identity, "manifest", key)
+				continue
+			}
+			if err := p.client.DeleteManifest(ctx, e.identity, key); err != nil {
+				return err
+			}
+			prunedManifests.Inc()
+			lgr.Infow("prune_deleted_manifest", "identity", e.identity, "manifest", key)
+		}
+	}
+
+	var unreferenced []digest.ForRestore
+	if err := p.client.ListBlobs(ctx, func(digests digest.ForRestore) bool {
+		if _, ok := referenced.blobs[digests]; !ok {
+			unreferenced = append(unreferenced, digests)
+		}
+		return true
+	}); err != nil {
+		return err
+	}
+
+	// A backup that found a blob already uploaded may not have written its manifest when marking
+	// listed manifests. Pick up any written since, by backups started within the grace period.
+	if err := p.markSince(ctx, unixtime.Seconds(protectAfter.Unix()), referenced); err != nil {
+		return err
+	}
+	candidates, previous, state, err := p.sweepable(ctx, unreferenced, referenced, markedAt)
+	if err != nil {
+		return err
+	}
+
+	var blobCount int
+	var blobBytes int64
+	for _, digests := range candidates {
+		info, err := p.client.StatBlob(ctx, digests)
+		if err == bucket.NotFound {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if info.LastModified.After(protectAfter) {
+			// Possibly uploaded by a backup that hasn't written its manifest yet.
+			lgr.Debugw("prune_skipped_recent_blob", "digest", digests, "last_modified", info.LastModified)
+			continue
+		}
+		if info.RetainUntil.After(time.Now()) {
+			// Still locked, and backups may be skipping its upload based on their exists cache.
+			lgr.Debugw("prune_skipped_locked_blob", "digest", digests, "retain_until", info.RetainUntil)
+			continue
+		}
+		blobCount++
+		blobBytes += info.ContentLength
+		if p.dryRun {
+			lgr.Debugw("prune_would_delete_blob", "digest", digests, "size", info.ContentLength)
+			continue
+		}
+		if err := p.client.DeleteBlob(ctx, digests); err != nil {
+			return err
+		}
+		delete(state.UnreferencedBlobs, digests.URLSafe())
+		prunedBlobs.Inc()
+		prunedBytes.Add(float64(info.ContentLength))
+	}
+	// Only save the state when it changed, since on a versioned bucket every write keeps another version.
+	if !p.dryRun && !samePruneState(previous, state) {
+		if err := p.client.PutPruneState(ctx, state); err != nil {
+			return err
+		}
+	}
+
+	schemaCount, err := p.sweepSchemas(ctx, referenced.schemas, protectAfter)
+	if err != nil {
+		return err
+	}
+
+	// On a versioned bucket, everything deleted above is still stored until its noncurrent version is removed.
+	purged, err := p.client.PurgeNoncurrentVersions(ctx, p.dryRun)
+	if err != nil {
+		return err
+	}
+	if !p.dryRun {
+		purgedVersions.Add(float64(purged.Versions))
+		purgedBytes.Add(float64(purged.Bytes))
+	}
+
+	lgr.Infow("prune_complete", "dry_run", p.dryRun, "manifests", manifestCount, "blobs", blobCount, "bytes", blobBytes, "pending_blobs", len(state.UnreferencedBlobs), "schemas", schemaCount, "purged_versions", purged.Versions, "purged_bytes", purged.Bytes, "purged_delete_markers", purged.DeleteMarkers)
+	return nil
+}
+
+func samePruneState(a, b bucket.PruneState) bool {
+	if len(a.UnreferencedBlobs) != len(b.UnreferencedBlobs) {
+		return false
+	}
+	for key, firstSeen := range a.UnreferencedBlobs {
+		if other, ok := b.UnreferencedBlobs[key]; !ok || other != firstSeen {
+			return false
+		}
+	}
+	return true
+}
+
+// sweepable returns the unreferenced blobs that a previous run also found unreferenced at least
+// the grace period ago, the previous run's state, and the state to save for the next run. Deleting only those means a backup
+// that reused a blob just before one run has had the grace period to write its manifest.
+func (p *pruner) sweepable(ctx context.Context, unreferenced []digest.ForRestore, referenced references, markedAt time.Time) ([]digest.ForRestore, bucket.PruneState, bucket.PruneState, error) {
+	previous, err := p.client.GetPruneState(ctx)
+	if err != nil && err != bucket.NotFound {
+		return nil, previous, previous, err
+	}
+	state := bucket.PruneState{
+		UnreferencedBlobs: make(map[string]unixtime.Seconds, len(unreferenced)),
+	}
+	sweepBefore := unixtime.Seconds(markedAt.Add(-p.gracePeriod).Unix())
+	var candidates []digest.ForRestore
+	for _, digests := range unreferenced {
+		if _, ok := referenced.blobs[digests]; ok {
+			continue
+		}
+		key := digests.URLSafe()
+		firstSeen, ok := previous.UnreferencedBlobs[key]
+		if !ok {
+			firstSeen = unixtime.Seconds(markedAt.Unix())
+		}
+		state.UnreferencedBlobs[key] = firstSeen
+		if ok && firstSeen <= sweepBefore {
+			candidates = append(candidates, digests)
+		}
+	}
+	return candidates, previous, state, nil
+}
+
+func (p *pruner) sweepSchemas(ctx context.Context, referenced map[string]struct{}, protectAfter time.Time) (int, error) {
+	lgr := zap.S()
+	var candidates []string
+	if err := p.client.ListSchemas(ctx, func(id string) bool {
+		if _, ok := referenced[id]; !ok {
+			candidates = append(candidates, id)
+		}
+		return true
+	}); err != nil {
+		return 0, err
+	}
+
+	var count int
+	for _, id := range candidates {
+		info, err := p.client.StatSchema(ctx, id)
+		if err == bucket.NotFound {
+			continue
+		} else if err != nil {
+			return count, err
+		}
+		if info.LastModified.After(protectAfter) {
+			// Possibly uploaded by a snapshot that hasn't written its manifest yet.
+			continue
+		}
+		count++
+		if p.dryRun {
+			lgr.Debugw("prune_would_delete_schema", "schema", id)
+			continue
+		}
+		if err := p.client.DeleteSchema(ctx, id); err != nil {
+			return count, err
+		}
+		prunedSchemas.Inc()
+	}
+	return count, nil
+}
+
+func (p *pruner) mark(ctx context.Context, protectAfter unixtime.Seconds) ([]expiredManifests, references, error) {
+	lgr := zap.S()
+	targeted := make(map[string]struct{}, len(p.clusters))
+	for _, cluster := range p.clusters {
+		targeted[cluster] = struct{}{}
+	}
+
+	var referenced references
+	clusters, err := p.client.ListClusters(ctx)
+	if err != nil {
+		return nil, referenced, err
+	}
+
+	var expired []expiredManifests
+	referenced.blobs = make(map[digest.ForRestore]struct{})
+	referenced.schemas = make(map[string]struct{})
+	for _, cluster := range clusters {
+		_, isTargeted := targeted[cluster]
+		isTargeted = isTargeted || len(targeted) == 0
+
+		identities, err := p.client.ListHostNames(ctx, cluster)
+		if err != nil {
+			return nil, referenced, err
+		}
+		for _, identity := range identities {
+			keep, err := p.client.ListManifestsStrict(ctx, identity, 0, 0)
+			if err != nil {
+				return nil, referenced, err
+			}
+			if isTargeted {
+				var remove manifests.ManifestKeys
+				keep, remove = p.policy.Apply(keep, protectAfter)
+				if len(remove) > 0 {
+					expired = append(expired, expiredManifests{
+						identity: identity,
+						keys:     remove,
+					})
+				}
+				lgr.Infow("prune_host_plan", "identity", identity, "keep", len(keep), "remove", len(remove))
+			}
+
+			kept, err := p.client.GetManifests(ctx, identity, keep)
+			if err != nil {
+				return nil, referenced, err
+			}
+			for _, m := range kept {
+				referenced.add(m)
+			}
+		}
+	}
+	return expired, referenced, nil
+}
+
+// markSince adds the blobs and schemas of every manifest from startAfter onwards to referenced,
+// in every cluster.
+func (p *pruner) markSince(ctx context.Context, startAfter unixtime.Seconds, referenced references) error {
+	clusters, err := p.client.ListClusters(ctx)
+	if err != nil {
+		return err
+	}
+	for _, cluster := range clusters {
+		identities, err := p.client.ListHostNames(ctx, cluster)
+		if err != nil {
+			return err
+		}
+		for _, identity := range identities {
+			keys, err := p.client.ListManifestsStrict(ctx, identity, startAfter, 0)
+			if err != nil {
+				return err
+			}
+			recent, err := p.client.GetManifests(ctx, identity, keys)
+			if err != nil {
+				return err
+			}
+			for _, m := range recent {
+				referenced.add(m)
+			}
+		}
+	}
+	return nil
+}
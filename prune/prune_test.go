@@ -0,0 +1,327 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prune
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+	"github.com/retailnext/cassandrabackup/bucket"
+	"github.com/retailnext/cassandrabackup/digest"
+	"github.com/retailnext/cassandrabackup/manifests"
+	"github.com/retailnext/cassandrabackup/paranoid"
+	"github.com/retailnext/cassandrabackup/unixtime"
+)
+
+func putTestBlob(t *testing.T, client *bucket.Client, contents string) digest.ForRestore {
+	ctx := context.Background()
+	f, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	}()
+	if _, err := f.WriteString(contents); err != nil {
+		t.Fatal(err)
+	}
+	parFile, err := paranoid.NewFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	digests, err := digest.GetUncached(ctx, parFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.PutBlob(ctx, parFile, digests); err != nil {
+		t.Fatal(err)
+	}
+	return digests.ForRestore()
+}
+
+func TestPrune(t *testing.T) {
+	ctx := context.Background()
+	client := bucket.NewClient(bucket.NewMemoryBackend(), "")
+	identity := manifests.NodeIdentity{Cluster: "test-cluster", Hostname: "test-host"}
+	other := manifests.NodeIdentity{Cluster: "other-cluster", Hostname: "test-host"}
+
+	shared := putTestBlob(t, client, "shared")
+	old := putTestBlob(t, client, "old")
+	current := putTestBlob(t, client, "current")
+	otherCluster := putTestBlob(t, client, "other")
+	oldSchema, err := client.PutSchema(ctx, []byte("CREATE KEYSPACE old;\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	currentSchema, err := client.PutSchema(ctx, []byte("CREATE KEYSPACE current;\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m1 := manifests.Manifest{
+		Time:         1 * day,
+		ManifestType: manifests.ManifestTypeSnapshot,
+		Schema:       oldSchema,
+		DataFiles: map[string]digest.ForRestore{
+			"ks/t-1/md-1-big-Data.db": shared,
+			"ks/t-1/md-2-big-Data.db": old,
+		},
+	}
+	m2 := manifests.Manifest{
+		Time:         2 * day,
+		ManifestType: manifests.ManifestTypeSnapshot,
+		Schema:       currentSchema,
+		DataFiles: map[string]digest.ForRestore{
+			"ks/t-1/md-1-big-Data.db": shared,
+			"ks/t-1/md-3-big-Data.db": current,
+		},
+	}
+	m3 := manifests.Manifest{
+		Time:         1 * day,
+		ManifestType: manifests.ManifestTypeSnapshot,
+		DataFiles: map[string]digest.ForRestore{
+			"ks/t-1/md-1-big-Data.db": otherCluster,
+		},
+	}
+	for _, m := range []manifests.Manifest{m1, m2} {
+		if err := client.PutManifest(ctx, identity, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := client.PutManifest(ctx, other, m3); err != nil {
+		t.Fatal(err)
+	}
+
+	p := pruner{
+		client:   client,
+		policy:   Policy{KeepLast: 1},
+		clusters: []string{identity.Cluster},
+		iKnow:    true,
+	}
+	if err := p.run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// Blobs are only deleted once a second run still finds them unreferenced.
+	if _, err := client.StatBlob(ctx, old); err != nil {
+		t.Fatalf("expected %v to be kept until the next run: %v", old, err)
+	}
+	if err := p.run(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := client.ListManifests(ctx, identity, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(keys, manifests.ManifestKeys{m2.Key()}); diff != nil {
+		t.Fatal(diff)
+	}
+	for _, digests := range []digest.ForRestore{shared, current, otherCluster} {
+		if _, err := client.StatBlob(ctx, digests); err != nil {
+			t.Fatalf("expected %v to be kept: %v", digests, err)
+		}
+	}
+	if _, err := client.StatBlob(ctx, old); err != bucket.NotFound {
+		t.Fatalf("expected=%v actual=%v", bucket.NotFound, err)
+	}
+	if _, err := client.StatSchema(ctx, currentSchema); err != nil {
+		t.Fatalf("expected current schema to be kept: %v", err)
+	}
+	if _, err := client.StatSchema(ctx, oldSchema); err != bucket.NotFound {
+		t.Fatalf("expected=%v actual=%v", bucket.NotFound, err)
+	}
+}
+
+func TestPruneReferencedBetweenRuns(t *testing.T) {
+	ctx := context.Background()
+	client := bucket.NewClient(bucket.NewMemoryBackend(), "")
+	identity := manifests.NodeIdentity{Cluster: "test-cluster", Hostname: "test-host"}
+	reused := putTestBlob(t, client, "reused")
+	garbage := putTestBlob(t, client, "garbage")
+
+	p := pruner{
+		client: client,
+		policy: Policy{KeepLast: 1},
+		iKnow:  true,
+	}
+	if err := p.run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	state, err := client.GetPruneState(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.UnreferencedBlobs) != 2 {
+		t.Fatalf("expected both blobs to be pending: %v", state.UnreferencedBlobs)
+	}
+
+	// A backup that found the blob already uploaded writes a manifest referencing it.
+	m := manifests.Manifest{
+		Time:         1 * day,
+		ManifestType: manifests.ManifestTypeSnapshot,
+		DataFiles: map[string]digest.ForRestore{
+			"ks/t-1/md-1-big-Data.db": reused,
+		},
+	}
+	if err := client.PutManifest(ctx, identity, m); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.StatBlob(ctx, reused); err != nil {
+		t.Fatalf("expected reused blob to be kept: %v", err)
+	}
+	if _, err := client.StatBlob(ctx, garbage); err != bucket.NotFound {
+		t.Fatalf("expected=%v actual=%v", bucket.NotFound, err)
+	}
+	state, err = client.GetPruneState(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.UnreferencedBlobs) != 0 {
+		t.Fatalf("expected no pending blobs: %v", state.UnreferencedBlobs)
+	}
+}
+
+// versionsByKey counts the versions and delete markers kept of each key.
+func versionsByKey(t *testing.T, backend *bucket.MemoryBackend) map[string]int {
+	counts := make(map[string]int)
+	if err := backend.ListVersions(context.Background(), "", func(versions []bucket.ObjectVersion) bool {
+		for _, version := range versions {
+			counts[version.Key]++
+		}
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return counts
+}
+
+func TestPruneVersionedBucket(t *testing.T) {
+	ctx := context.Background()
+	const retention = 200 * time.Millisecond
+	backend := bucket.NewVersionedMemoryBackend(retention)
+	client := bucket.NewClient(backend, "")
+	identity := manifests.NodeIdentity{Cluster: "test-cluster", Hostname: "test-host"}
+	kept := putTestBlob(t, client, "kept")
+	old := putTestBlob(t, client, "old")
+	for i, digests := range []digest.ForRestore{old, kept} {
+		m := manifests.Manifest{
+			Time:         unixtime.Seconds(i+1) * day,
+			ManifestType: manifests.ManifestTypeSnapshot,
+			DataFiles: map[string]digest.ForRestore{
+				"ks/t-1/md-1-big-Data.db": digests,
+			},
+		}
+		if err := client.PutManifest(ctx, identity, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p := pruner{
+		client: client,
+		policy: Policy{KeepLast: 1},
+	}
+	if err := p.run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// The expired manifest's version is still locked, so only a delete marker was added.
+	var markedKeys int
+	for _, count := range versionsByKey(t, backend) {
+		if count == 2 {
+			markedKeys++
+		}
+	}
+	if markedKeys != 1 {
+		t.Fatalf("expected the expired manifest to be kept behind a delete marker: %v", versionsByKey(t, backend))
+	}
+
+	time.Sleep(retention + 100*time.Millisecond)
+	for i := 0; i < 2; i++ {
+		if err := p.run(ctx); err != nil {
+			t.Fatal(err)
+		}
+		// Only the kept blob, the kept manifest and a single version of the prune state remain.
+		counts := versionsByKey(t, backend)
+		if len(counts) != 3 {
+			t.Fatalf("expected 3 keys, got %v", counts)
+		}
+		for key, count := range counts {
+			if count != 1 {
+				t.Fatalf("expected a single version of %s, got %d", key, count)
+			}
+		}
+	}
+	if _, err := client.StatBlob(ctx, kept); err != nil {
+		t.Fatalf("expected kept blob to be kept: %v", err)
+	}
+}
+
+func TestPruneSafety(t *testing.T) {
+	ctx := context.Background()
+	backend := bucket.NewMemoryBackend()
+	client := bucket.NewClient(backend, "")
+	identity := manifests.NodeIdentity{Cluster: "test-cluster", Hostname: "test-host"}
+	blob := putTestBlob(t, client, "blob")
+
+	p := pruner{
+		client: client,
+		policy: Policy{KeepLast: 1},
+	}
+	if err := p.run(ctx); err != ObjectLockRequired {
+		t.Fatalf("expected=%v actual=%v", ObjectLockRequired, err)
+	}
+
+	// A manifest prune can't parse may reference anything, so nothing can be deleted.
+	m := manifests.Manifest{
+		Time:         1 * day,
+		ManifestType: manifests.ManifestTypeSnapshot,
+	}
+	if err := client.PutManifest(ctx, identity, m); err != nil {
+		t.Fatal(err)
+	}
+	var manifestKey string
+	for prefix := "manifests/"; manifestKey == ""; {
+		if err := backend.List(ctx, prefix, "", func(keys, prefixes []string) bool {
+			if len(keys) > 0 {
+				manifestKey = keys[0]
+			} else {
+				prefix = prefixes[0]
+			}
+			return true
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := backend.PutDocument(ctx, path.Join(path.Dir(manifestKey), "not-a-manifest"), []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	p.iKnow = true
+	for i := 0; i < 2; i++ {
+		if err := p.run(ctx); err != bucket.UnparseableManifestKey {
+			t.Fatalf("expected=%v actual=%v", bucket.UnparseableManifestKey, err)
+		}
+	}
+	if _, err := client.StatBlob(ctx, blob); err != nil {
+		t.Fatalf("expected blob to be kept: %v", err)
+	}
+}
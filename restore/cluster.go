@@ -28,10 +28,14 @@ import (
 func RestoreCluster(ctx context.Context) error {
 	lgr := zap.S()
 
+	tables, err := manifests.NewTableFilter(*clusterCmdTables, *clusterCmdExclude, *clusterCmdSystemKeyspaces)
+	if err != nil {
+		return err
+	}
 	filter := plan.Filter{
+		Tables:         tables,
 		IncludeIndexes: !*clusterCmdSkipIndexes,
 	}
-	filter.Build(*clusterCmdTables)
 
 	identities := nodeIdentitiesForCluster(ctx, clusterCmdCluster, clusterCmdHostnamePattern)
 	lgr.Infow("selected_hosts", "identities", identities)
@@ -55,6 +59,9 @@ func RestoreCluster(ctx context.Context) error {
 		hostLgr.Infow("selected_manifests", "base", nodePlan.SelectedManifests[0], "additional", nodePlan.SelectedManifests[1:])
 
 		nodePlan.Filter(filter)
+		if err := remap(&nodePlan, *clusterCmdMap, *clusterCmdTableIDsFrom); err != nil {
+			return err
+		}
 
 		dp.addHost(hostIdentity.Hostname, nodePlan)
 	}
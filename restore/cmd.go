@@ -14,6 +14,8 @@ var (
 
 	HostCmd    = Cmd.Command("host", "Restore this host from backup")
 	ClusterCmd = Cmd.Command("cluster", "Download from multiple hosts' backups")
+	SchemaCmd  = Cmd.Command("schema", "Write the CQL schema backed up with a snapshot")
+	LiveCmd    = Cmd.Command("live", "Load tables from this host's backup into its running Cassandra")
 
 	hostCmdDryRun            = HostCmd.Flag("dry-run", "Don't actually download files").Bool()
 	hostCmdAllowChangedFiles = HostCmd.Flag("allow-changed", "Allow restoration of files that changed between manifests").Bool()
@@ -22,6 +24,20 @@ var (
 	hostCmdCluster           = HostCmd.Flag("cluster", "Use a different cluster name when selecting a backup to restore.").String()
 	hostCmdHostname          = HostCmd.Flag("hostname", "Use a specific hostname when selecting a backup to restore.").String()
 	hostCmdHostnamePattern   = HostCmd.Flag("hostname-pattern", "Use a prefix pattern when selecting a backup to restore.").String()
+	hostCmdRestoreMtimes     = HostCmd.Flag("restore-mtimes", "Set the modification times of restored files to those recorded in the manifest").Bool()
+	hostCmdCommitLogUntil    = HostCmd.Flag("commitlog-until", "Download archived commitlog segments and configure Cassandra to replay them up to this time (unix seconds)").Int64()
+	hostCmdCommitLogDir      = HostCmd.Flag("commitlog-directory", "Where to download commitlog segments for replay").Default("/var/lib/cassandra/commitlog_restore").String()
+	hostCmdInclude           = HostCmd.Flag("include", "Only restore tables matching these patterns (keyspace, keyspace.table, globs, or re:<regexp>)").Strings()
+	hostCmdExclude           = HostCmd.Flag("exclude", "Don't restore tables matching these patterns").Strings()
+	hostCmdSystemKeyspaces   = HostCmd.Flag("system-keyspaces", "Restore system keyspaces. Use --no-system-keyspaces to skip them.").Default("true").Bool()
+	hostCmdMap               = HostCmd.Flag("map", "Restore a keyspace or table under another name (prod_ks=staging_ks or prod_ks.events=staging_ks.events)").PlaceHolder("SOURCE=TARGET").Strings()
+	hostCmdTableIDsFrom      = HostCmd.Flag("table-ids-from", "Name table directories after the target tables' IDs, looked up from this node's system_schema.tables").PlaceHolder("ADDRESS").String()
+	hostCmdCommitLogConfig   = HostCmd.Flag("commitlog-archiving-properties", "The commitlog archiving config file to add restore settings to").Default("/etc/cassandra/commitlog_archiving.properties").String()
+	hostCmdAllowRunning      = HostCmd.Flag("allow-running", "Restore even if Cassandra appears to be running").Bool()
+	hostCmdAllowCluster      = HostCmd.Flag("allow-cluster-mismatch", "Restore even if cluster_name in cassandra.yaml differs from the backup's cluster").Bool()
+	hostCmdAllowTokens       = HostCmd.Flag("allow-token-mismatch", "Restore even if initial_token in cassandra.yaml differs from the backup's tokens").Bool()
+	hostCmdWriteTokens       = HostCmd.Flag("write-tokens", "Set initial_token and num_tokens in cassandra.yaml to the backup's tokens, keeping a copy of the original").Bool()
+	hostCmdAllowLowSpace     = HostCmd.Flag("allow-low-disk-space", "Restore even if the data directory's filesystem has less free space than the restore needs").Bool()
 
 	clusterCmdDryRun          = ClusterCmd.Flag("dry-run", "Don't actually download files").Bool()
 	clusterCmdTargetDirectory = ClusterCmd.Flag("target", "A subdirectory will be created under this for each host.").Required().String()
@@ -29,6 +45,29 @@ var (
 	clusterCmdNotAfter        = ClusterCmd.Flag("not-after", "Ignore manifests after this time (unix seconds)").Int64()
 	clusterCmdCluster         = ClusterCmd.Flag("cluster", "Download files for hosts in this cluster").Required().String()
 	clusterCmdHostnamePattern = ClusterCmd.Flag("hostname-pattern", "Download for hosts matching this prefix.").Required().String()
-	clusterCmdTables          = ClusterCmd.Flag("table", "Download files for these tables (keyspace.table)").Required().Strings()
+	clusterCmdTables          = ClusterCmd.Flag("table", "Download files for tables matching these patterns (keyspace, keyspace.table, globs, or re:<regexp>)").Required().Strings()
+	clusterCmdExclude         = ClusterCmd.Flag("exclude", "Don't download files for tables matching these patterns").Strings()
+	clusterCmdSystemKeyspaces = ClusterCmd.Flag("system-keyspaces", "Allow tables in system keyspaces to match").Bool()
 	clusterCmdSkipIndexes     = ClusterCmd.Flag("skip-indexes", "Skip downloading indexes").Default("True").Bool()
+	clusterCmdMap             = ClusterCmd.Flag("map", "Restore a keyspace or table under another name (prod_ks=staging_ks or prod_ks.events=staging_ks.events)").PlaceHolder("SOURCE=TARGET").Strings()
+	clusterCmdTableIDsFrom    = ClusterCmd.Flag("table-ids-from", "Name table directories after the target tables' IDs, looked up from this node's system_schema.tables").PlaceHolder("ADDRESS").String()
+
+	schemaCmdNotAfter        = SchemaCmd.Flag("not-after", "Ignore snapshots after this time (unix seconds)").Int64()
+	schemaCmdCluster         = SchemaCmd.Flag("cluster", "Use a different cluster name when selecting a backup.").String()
+	schemaCmdHostname        = SchemaCmd.Flag("hostname", "Use a specific hostname when selecting a backup.").String()
+	schemaCmdHostnamePattern = SchemaCmd.Flag("hostname-pattern", "Use a prefix pattern when selecting a backup.").String()
+	schemaCmdOutputFile      = SchemaCmd.Flag("output-file", "Write the schema to this file, or - for stdout").Default("schema.cql").String()
+
+	liveCmdDryRun            = LiveCmd.Flag("dry-run", "Don't actually download or load files").Bool()
+	liveCmdAllowChangedFiles = LiveCmd.Flag("allow-changed", "Allow restoration of files that changed between manifests").Bool()
+	liveCmdNotBefore         = LiveCmd.Flag("not-before", "Ignore manifests before this time (unix seconds)").Int64()
+	liveCmdNotAfter          = LiveCmd.Flag("not-after", "Ignore manifests after this time (unix seconds)").Int64()
+	liveCmdCluster           = LiveCmd.Flag("cluster", "Use a different cluster name when selecting a backup to restore.").String()
+	liveCmdHostname          = LiveCmd.Flag("hostname", "Use a specific hostname when selecting a backup to restore.").String()
+	liveCmdHostnamePattern   = LiveCmd.Flag("hostname-pattern", "Use a prefix pattern when selecting a backup to restore.").String()
+	liveCmdTables            = LiveCmd.Flag("table", "Restore tables matching these patterns (keyspace, keyspace.table, globs, or re:<regexp>)").Required().Strings()
+	liveCmdExclude           = LiveCmd.Flag("exclude", "Don't restore tables matching these patterns").Strings()
+	liveCmdMap               = LiveCmd.Flag("map", "Restore a keyspace or table under another name (prod_ks=staging_ks or prod_ks.events=staging_ks.events)").PlaceHolder("SOURCE=TARGET").Strings()
+	liveCmdStagingDirectory  = LiveCmd.Flag("staging-directory", "Download files here before loading them. Must be on the same filesystem as the data directory.").Default("/var/lib/cassandra/restore_staging").String()
+	liveCmdMethod            = LiveCmd.Flag("method", "Load with nodetool refresh (3.x) or nodetool import (4.0+). auto picks based on the installed version.").Default("auto").Enum("auto", "refresh", "import")
 )
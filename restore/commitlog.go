@@ -0,0 +1,108 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package restore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/retailnext/cassandrabackup/unixtime"
+	"github.com/retailnext/cassandrabackup/writefile"
+)
+
+// restorePointInTimeFormat is what Cassandra expects for restore_point_in_time, in UTC.
+const restorePointInTimeFormat = "2006:01:02 15:04:05"
+
+var commitLogRestoreProperties = []string{"restore_command", "restore_directories", "restore_point_in_time"}
+
+// configureCommitLogRestore sets the restore properties in Cassandra's commitlog archiving config,
+// keeping the rest of it (such as archive_command) intact.
+func configureCommitLogRestore(fileName, directory string, until unixtime.Seconds) error {
+	fileName, err := filepath.Abs(fileName)
+	if err != nil {
+		return err
+	}
+	dirInfo, err := os.Stat(filepath.Dir(fileName))
+	if err != nil {
+		return err
+	}
+	target := writefile.Config{
+		Directory:     filepath.Dir(fileName),
+		DirectoryMode: dirInfo.Mode().Perm(),
+		FileMode:      writefile.DefaultFileMode,
+	}
+	existing, err := ioutil.ReadFile(fileName)
+	if err == nil {
+		// Keep the original's mode and owner, since cassandra has to be able to read it.
+		info, err := os.Stat(fileName)
+		if err != nil {
+			return err
+		}
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		if !ok {
+			panic("restore: unable to check file ownership")
+		}
+		target.FileMode = info.Mode().Perm()
+		target.FileUID = int(stat.Uid)
+		target.FileGID = int(stat.Gid)
+		target.EnsureFileOwnership = true
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	// Replaced by rename so that cassandra never sees a partial config.
+	updated := commitLogRestoreConfig(string(existing), directory, until)
+	return target.WriteFile(filepath.Base(fileName), func(file *os.File) error {
+		if _, err := file.WriteString(updated); err != nil {
+			return err
+		}
+		return file.Sync()
+	})
+}
+
+func commitLogRestoreConfig(existing, directory string, until unixtime.Seconds) string {
+	var lines []string
+	for _, line := range strings.Split(existing, "\n") {
+		if !isCommitLogRestoreProperty(line) {
+			lines = append(lines, line)
+		}
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	lines = append(lines,
+		"restore_command=/bin/cp -f %from %to",
+		"restore_directories="+directory,
+		"restore_point_in_time="+time.Unix(int64(until), 0).UTC().Format(restorePointInTimeFormat),
+	)
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func isCommitLogRestoreProperty(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	for _, property := range commitLogRestoreProperties {
+		if strings.HasPrefix(trimmed, property) {
+			rest := strings.TrimSpace(trimmed[len(property):])
+			if rest == "" || rest[0] == '=' || rest[0] == ':' {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package restore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestCommitLogRestoreConfig(t *testing.T) {
+	existing := `# commitlog archiving
+archive_command=/usr/bin/cassandrabackup backup commitlog %path
+restore_command=
+restore_directories = /old
+restore_point_in_time:2000:01:01 00:00:00
+precision=MICROSECONDS
+
+`
+	expected := `# commitlog archiving
+archive_command=/usr/bin/cassandrabackup backup commitlog %path
+precision=MICROSECONDS
+restore_command=/bin/cp -f %from %to
+restore_directories=/var/lib/cassandra/commitlog_restore
+restore_point_in_time=2019:06:01 12:30:00
+`
+	actual := commitLogRestoreConfig(existing, "/var/lib/cassandra/commitlog_restore", 1559392200)
+	if diff := deep.Equal(actual, expected); diff != nil {
+		t.Fatal(diff)
+	}
+	if diff := deep.Equal(commitLogRestoreConfig(actual, "/var/lib/cassandra/commitlog_restore", 1559392200), expected); diff != nil {
+		t.Fatal(diff)
+	}
+}
+
+func TestConfigureCommitLogRestore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "restore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fileName := filepath.Join(dir, "commitlog_archiving.properties")
+	if err := ioutil.WriteFile(fileName, []byte("archive_command=\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := configureCommitLogRestore(fileName, "/restore", 1559392200); err != nil {
+		t.Fatal(err)
+	}
+
+	contents, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "archive_command=\nrestore_command=/bin/cp -f %from %to\nrestore_directories=/restore\nrestore_point_in_time=2019:06:01 12:30:00\n"
+	if diff := deep.Equal(string(contents), expected); diff != nil {
+		t.Fatal(diff)
+	}
+	info, err := os.Stat(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode %v", info.Mode())
+	}
+
+	leftovers, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leftovers) != 1 {
+		t.Errorf("unexpected files: %v", leftovers)
+	}
+}
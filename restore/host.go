@@ -18,6 +18,9 @@ import (
 	"context"
 	"errors"
 
+	"github.com/retailnext/cassandrabackup/cassandraconfig"
+	"github.com/retailnext/cassandrabackup/cassandraversion"
+	"github.com/retailnext/cassandrabackup/digest"
 	"github.com/retailnext/cassandrabackup/manifests"
 	"github.com/retailnext/cassandrabackup/nodeidentity"
 	"github.com/retailnext/cassandrabackup/restore/plan"
@@ -28,12 +31,22 @@ import (
 var NoSnapshotsFound = errors.New("no snapshots found for host")
 var NoBackupsFound = errors.New("no backups found for host")
 var ChangesDetected = errors.New("file changes detected")
+var NoTokensFound = errors.New("no tokens found in backup")
+
+const dataDirectory = "/var/lib/cassandra/data"
 
 func RestoreHost(ctx context.Context) error {
 	identity := nodeidentity.ForRestore(ctx, hostCmdCluster, hostCmdHostname, hostCmdHostnamePattern)
 	lgr := zap.S().With("identity", identity)
 
-	nodePlan, err := plan.Create(ctx, identity, unixtime.Seconds(*hostCmdNotBefore), unixtime.Seconds(*hostCmdNotAfter))
+	notAfter := unixtime.Seconds(*hostCmdNotAfter)
+	commitLogUntil := unixtime.Seconds(*hostCmdCommitLogUntil)
+	if commitLogUntil > 0 && (notAfter == 0 || notAfter > commitLogUntil+1) {
+		// Data flushed after the point in time would contain later writes.
+		notAfter = commitLogUntil + 1
+	}
+
+	nodePlan, err := plan.Create(ctx, identity, unixtime.Seconds(*hostCmdNotBefore), notAfter)
 	if err != nil {
 		return err
 	}
@@ -47,24 +60,133 @@ func RestoreHost(ctx context.Context) error {
 
 	lgr.Infow("selected_manifests", "base", nodePlan.SelectedManifests[0], "additional", nodePlan.SelectedManifests[1:])
 
-	if len(nodePlan.ChangedFiles) > 0 {
-		for name, history := range nodePlan.ChangedFiles {
-			for _, entry := range history {
-				lgr.Infow("file_changed", "name", name, "digest", entry.Digest, "manifest", entry.Manifest)
-			}
+	tables, err := manifests.NewTableFilter(*hostCmdInclude, *hostCmdExclude, *hostCmdSystemKeyspaces)
+	if err != nil {
+		return err
+	}
+	nodePlan.Filter(plan.Filter{Tables: tables, IncludeIndexes: true})
+	if err := remap(&nodePlan, *hostCmdMap, *hostCmdTableIDsFrom); err != nil {
+		return err
+	}
+	if err := checkChangedFiles(nodePlan, *hostCmdAllowChangedFiles); err != nil {
+		return err
+	}
+
+	checkReleaseVersion(nodePlan.ReleaseVersion)
+
+	if *hostCmdWriteTokens && len(nodePlan.Tokens) == 0 {
+		return NoTokensFound
+	}
+	checks := safetyChecks{
+		allowRunning:  *hostCmdAllowRunning,
+		allowCluster:  *hostCmdAllowCluster,
+		allowTokens:   *hostCmdAllowTokens,
+		allowLowSpace: *hostCmdAllowLowSpace,
+	}
+	if *hostCmdWriteTokens {
+		// cassandra.yaml is going to be updated to match.
+		checks.allowTokens = true
+	}
+	if err := checkSafety(ctx, identity, nodePlan, checks); err != nil {
+		if !*hostCmdDryRun {
+			return err
 		}
-		if !*hostCmdAllowChangedFiles {
-			return ChangesDetected
+		lgr.Warnw("restore_would_be_refused", "err", err)
+	}
+
+	var commitLogs map[string]digest.ForRestore
+	if commitLogUntil > 0 {
+		commitLogs, err = plan.CommitLogs(ctx, identity, nodePlan.SelectedManifests[0].Time, commitLogUntil)
+		if err != nil {
+			return err
 		}
+		lgr.Infow("selected_commitlogs", "count", len(commitLogs), "until", commitLogUntil)
 	}
 
 	if *hostCmdDryRun {
 		for name, file := range nodePlan.Files {
 			lgr.Infow("would_download", "name", name, "digest", file)
 		}
+		for name, segment := range commitLogs {
+			lgr.Infow("would_download_commitlog", "name", name, "digest", segment)
+		}
+		if *hostCmdWriteTokens {
+			lgr.Infow("would_write_tokens", "config", cassandraconfig.ConfigFileName, "tokens", nodePlan.Tokens)
+		}
 		return nil
 	}
 
-	w := newWorker("/var/lib/cassandra/data", true)
-	return w.restoreFiles(ctx, nodePlan.Files)
+	w := newWorker(dataDirectory, true)
+	if *hostCmdRestoreMtimes {
+		w.fileInfo = nodePlan.FileInfo
+	}
+	if err := w.restoreFiles(ctx, nodePlan.Files); err != nil {
+		return err
+	}
+
+	if commitLogUntil > 0 {
+		if err := newWorker(*hostCmdCommitLogDir, true).restoreFiles(ctx, commitLogs); err != nil {
+			return err
+		}
+		if err := configureCommitLogRestore(*hostCmdCommitLogConfig, *hostCmdCommitLogDir, commitLogUntil); err != nil {
+			return err
+		}
+		lgr.Infow("configured_commitlog_restore", "config", *hostCmdCommitLogConfig, "until", commitLogUntil)
+	}
+
+	if *hostCmdWriteTokens {
+		backupName, err := cassandraconfig.WriteTokens(nodePlan.Tokens)
+		if err != nil {
+			return err
+		}
+		lgr.Infow("wrote_tokens", "config", cassandraconfig.ConfigFileName, "original", backupName, "tokens", len(nodePlan.Tokens))
+	}
+	return nil
+}
+
+// checkReleaseVersion warns if the installed Cassandra is older than the one that wrote the backup,
+// since it may not be able to read the sstables.
+func checkReleaseVersion(backupVersion string) {
+	lgr := zap.S()
+	if backupVersion == "" {
+		return
+	}
+	installed, err := cassandraversion.Installed()
+	if err != nil {
+		lgr.Warnw("cassandra_version_unknown", "backup", backupVersion, "err", err)
+		return
+	}
+	if cassandraversion.Compare(installed, backupVersion) < 0 {
+		lgr.Warnw("cassandra_version_older_than_backup", "installed", installed, "backup", backupVersion)
+	}
+}
+
+func checkChangedFiles(nodePlan plan.NodePlan, allowChanged bool) error {
+	lgr := zap.S()
+	if len(nodePlan.ChangedFiles) == 0 {
+		return nil
+	}
+	for name, history := range nodePlan.ChangedFiles {
+		for _, entry := range history {
+			lgr.Infow("file_changed", "name", name, "digest", entry.Digest, "manifest", entry.Manifest)
+		}
+	}
+	if !allowChanged {
+		return ChangesDetected
+	}
+	return nil
+}
+
+// remap renames the keyspaces and tables of a plan's files according to mapping specs.
+func remap(nodePlan *plan.NodePlan, specs []string, tableIDsFrom string) error {
+	mapping, err := plan.NewMapping(specs)
+	if err != nil {
+		return err
+	}
+	if tableIDsFrom != "" {
+		if err := mapping.LookupTableIDs(tableIDsFrom, *nodePlan); err != nil {
+			return err
+		}
+	}
+	return nodePlan.Remap(mapping)
 }
@@ -0,0 +1,302 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package restore
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/retailnext/cassandrabackup/cassandraconfig"
+	"github.com/retailnext/cassandrabackup/cassandraversion"
+	"github.com/retailnext/cassandrabackup/digest"
+	"github.com/retailnext/cassandrabackup/manifests"
+	"github.com/retailnext/cassandrabackup/nodeidentity"
+	"github.com/retailnext/cassandrabackup/nodetool"
+	"github.com/retailnext/cassandrabackup/restore/plan"
+	"github.com/retailnext/cassandrabackup/systemlocal"
+	"github.com/retailnext/cassandrabackup/unixtime"
+	"go.uber.org/zap"
+)
+
+var NoTableFilesFound = errors.New("no sstables found for the selected tables")
+var UnrecognizedTableFile = errors.New("unrecognized file in a table directory")
+
+const (
+	loadMethodRefresh = "refresh"
+	loadMethodImport  = "import"
+)
+
+// sstableNameExpr matches sstable component file names like md-12-big-Data.db, or with the
+// UUID generations of Cassandra 4.1+ like nb-3fw2_0a3k_1x2ph2ljbq59v5pvvw-big-Data.db.
+var sstableNameExpr = regexp.MustCompile(`^([a-z]+)-([0-9]+|[0-9a-z]{4}_[0-9a-z]{4}_[0-9a-z]{18})-([a-z]+)-([A-Za-z0-9.]+)$`)
+
+// snapshotMetadataFiles are written into snapshot directories alongside the sstables.
+var snapshotMetadataFiles = map[string]struct{}{
+	"manifest.json": {},
+	"schema.cql":    {},
+}
+
+func RestoreLive(ctx context.Context) error {
+	identity := nodeidentity.ForRestore(ctx, liveCmdCluster, liveCmdHostname, liveCmdHostnamePattern)
+	lgr := zap.S().With("identity", identity)
+
+	nodePlan, err := plan.Create(ctx, identity, unixtime.Seconds(*liveCmdNotBefore), unixtime.Seconds(*liveCmdNotAfter))
+	if err != nil {
+		return err
+	}
+	if len(nodePlan.SelectedManifests) == 0 {
+		return NoBackupsFound
+	}
+	if nodePlan.SelectedManifests[0].ManifestType != manifests.ManifestTypeSnapshot {
+		return NoSnapshotsFound
+	}
+	lgr.Infow("selected_manifests", "base", nodePlan.SelectedManifests[0], "additional", nodePlan.SelectedManifests[1:])
+
+	// Secondary indexes are rebuilt by Cassandra when loading, and system tables must not be loaded into a running node.
+	tables, err := manifests.NewTableFilter(*liveCmdTables, *liveCmdExclude, false)
+	if err != nil {
+		return err
+	}
+	nodePlan.Filter(plan.Filter{Tables: tables})
+	// Refresh finds the target table's directory itself, and import doesn't care about it.
+	if err := remap(&nodePlan, *liveCmdMap, ""); err != nil {
+		return err
+	}
+	if err := checkChangedFiles(nodePlan, *liveCmdAllowChangedFiles); err != nil {
+		return err
+	}
+
+	tableDirectories, err := groupByTableDirectory(nodePlan.Files)
+	if err != nil {
+		return err
+	}
+	if len(tableDirectories) == 0 {
+		return NoTableFilesFound
+	}
+	method, err := loadMethod(*liveCmdMethod)
+	if err != nil {
+		return err
+	}
+
+	if *liveCmdDryRun {
+		for _, tableDirectory := range sortedTableDirectories(tableDirectories) {
+			for name, file := range tableDirectories[tableDirectory] {
+				lgr.Infow("would_download", "name", name, "digest", file)
+			}
+			keyspace, table := splitTableDirectory(tableDirectory)
+			lgr.Infow("would_load", "keyspace", keyspace, "table", table, "method", method)
+		}
+		return nil
+	}
+
+	var address string
+	if method == loadMethodRefresh {
+		cfg, err := cassandraconfig.Load()
+		if err != nil {
+			return err
+		}
+		address = cfg.IPForClients()
+	}
+
+	for _, tableDirectory := range sortedTableDirectories(tableDirectories) {
+		w := newWorker(*liveCmdStagingDirectory, true)
+		if err := w.restoreFiles(ctx, tableDirectories[tableDirectory]); err != nil {
+			return err
+		}
+		if err := loadTable(method, address, tableDirectory); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadTable loads the sstables staged for tableDirectory ("keyspace/table-<id>") into the running node,
+// then removes what's left of the staging directory.
+func loadTable(method, address, tableDirectory string) error {
+	keyspace, table := splitTableDirectory(tableDirectory)
+	staged := filepath.Join(*liveCmdStagingDirectory, filepath.FromSlash(tableDirectory))
+
+	switch method {
+	case loadMethodImport:
+		if err := nodetool.Import(keyspace, table, staged); err != nil {
+			return err
+		}
+	case loadMethodRefresh:
+		id, err := systemlocal.GetTableID(address, keyspace, table)
+		if err != nil {
+			return err
+		}
+		live := filepath.Join(dataDirectory, keyspace, table+"-"+strings.Replace(id.String(), "-", "", -1))
+		if err := moveSSTables(staged, live); err != nil {
+			return err
+		}
+		if err := nodetool.Refresh(keyspace, table); err != nil {
+			return err
+		}
+	}
+	return os.RemoveAll(staged)
+}
+
+func splitTableDirectory(tableDirectory string) (string, string) {
+	keyspace, table := path.Split(tableDirectory)
+	if i := strings.LastIndex(table, "-"); i > 0 {
+		table = table[:i]
+	}
+	return strings.TrimSuffix(keyspace, "/"), table
+}
+
+func loadMethod(flag string) (string, error) {
+	if flag != "auto" {
+		return flag, nil
+	}
+	installed, err := cassandraversion.Installed()
+	if err != nil {
+		return "", err
+	}
+	if cassandraversion.Compare(installed, "4.0") >= 0 {
+		return loadMethodImport, nil
+	}
+	return loadMethodRefresh, nil
+}
+
+// groupByTableDirectory splits sstable files by the "keyspace/table-<id>" directory they belong in.
+// Secondary index files and snapshot metadata are dropped, since loading the table rebuilds or
+// doesn't need them. Anything else that isn't an sstable component is an error, rather than
+// loading a table without it.
+func groupByTableDirectory(files map[string]digest.ForRestore) (map[string]map[string]digest.ForRestore, error) {
+	result := make(map[string]map[string]digest.ForRestore)
+	for name, file := range files {
+		parts := strings.Split(name, "/")
+		if len(parts) == 4 && strings.HasPrefix(parts[2], ".") {
+			continue
+		}
+		if len(parts) == 3 {
+			if _, ok := snapshotMetadataFiles[parts[2]]; ok {
+				continue
+			}
+		}
+		if len(parts) != 3 || !sstableNameExpr.MatchString(parts[2]) {
+			zap.S().Errorw("unrecognized_table_file", "name", name)
+			return nil, UnrecognizedTableFile
+		}
+		tableDirectory := parts[0] + "/" + parts[1]
+		if result[tableDirectory] == nil {
+			result[tableDirectory] = make(map[string]digest.ForRestore)
+		}
+		result[tableDirectory][name] = file
+	}
+	return result, nil
+}
+
+func sortedTableDirectories(tableDirectories map[string]map[string]digest.ForRestore) []string {
+	result := make([]string, 0, len(tableDirectories))
+	for tableDirectory := range tableDirectories {
+		result = append(result, tableDirectory)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// moveSSTables moves staged sstables into a live table directory, giving them generations
+// that don't collide with the sstables already there.
+func moveSSTables(staged, live string) error {
+	lgr := zap.S()
+	stagedNames, err := readDirNames(staged)
+	if err != nil {
+		return err
+	}
+	liveNames, err := readDirNames(live)
+	if err != nil {
+		return err
+	}
+	for oldName, newName := range renameSSTables(stagedNames, liveNames) {
+		if err := os.Rename(filepath.Join(staged, oldName), filepath.Join(live, newName)); err != nil {
+			return err
+		}
+		lgr.Debugw("moved_sstable", "from", filepath.Join(staged, oldName), "to", filepath.Join(live, newName))
+	}
+	return nil
+}
+
+func readDirNames(directory string) ([]string, error) {
+	infos, err := ioutil.ReadDir(directory)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(infos))
+	for _, info := range infos {
+		if !info.IsDir() {
+			names = append(names, info.Name())
+		}
+	}
+	return names, nil
+}
+
+// renameSSTables maps staged sstable component names to names with generations above
+// any of the live ones. Components of the same sstable keep sharing a generation.
+// UUID generations can't collide, so those keep their names.
+func renameSSTables(staged, live []string) map[string]string {
+	var maxLive int
+	for _, name := range live {
+		if match := sstableNameExpr.FindStringSubmatch(name); match != nil {
+			if generation, err := strconv.Atoi(match[2]); err == nil && generation > maxLive {
+				maxLive = generation
+			}
+		}
+	}
+
+	var stagedGenerations []int
+	seen := make(map[int]struct{})
+	for _, name := range staged {
+		if match := sstableNameExpr.FindStringSubmatch(name); match != nil {
+			generation, err := strconv.Atoi(match[2])
+			if err != nil {
+				continue
+			}
+			if _, ok := seen[generation]; !ok {
+				seen[generation] = struct{}{}
+				stagedGenerations = append(stagedGenerations, generation)
+			}
+		}
+	}
+	sort.Ints(stagedGenerations)
+	newGenerations := make(map[int]int, len(stagedGenerations))
+	for i, generation := range stagedGenerations {
+		newGenerations[generation] = maxLive + i + 1
+	}
+
+	result := make(map[string]string)
+	for _, name := range staged {
+		match := sstableNameExpr.FindStringSubmatch(name)
+		if match == nil {
+			continue
+		}
+		generation, err := strconv.Atoi(match[2])
+		if err != nil {
+			result[name] = name
+			continue
+		}
+		result[name] = match[1] + "-" + strconv.Itoa(newGenerations[generation]) + "-" + match[3] + "-" + match[4]
+	}
+	return result
+}
@@ -0,0 +1,84 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package restore
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/retailnext/cassandrabackup/digest"
+)
+
+func TestRenameSSTables(t *testing.T) {
+	staged := []string{
+		"md-3-big-Data.db",
+		"md-3-big-Index.db",
+		"md-1-big-Data.db",
+		"md-1-big-TOC.txt",
+		"nb-3fw2_0a3k_1x2ph2ljbq59v5pvvw-big-Data.db",
+		"manifest.json",
+	}
+	live := []string{
+		"md-1-big-Data.db",
+		"md-7-big-Data.db",
+		"md-7-big-Digest.crc32",
+		"backups",
+	}
+	expected := map[string]string{
+		"md-1-big-Data.db":  "md-8-big-Data.db",
+		"md-1-big-TOC.txt":  "md-8-big-TOC.txt",
+		"md-3-big-Data.db":  "md-9-big-Data.db",
+		"md-3-big-Index.db": "md-9-big-Index.db",
+
+		"nb-3fw2_0a3k_1x2ph2ljbq59v5pvvw-big-Data.db": "nb-3fw2_0a3k_1x2ph2ljbq59v5pvvw-big-Data.db",
+	}
+	if diff := deep.Equal(renameSSTables(staged, live), expected); diff != nil {
+		t.Fatal(diff)
+	}
+}
+
+func TestGroupByTableDirectory(t *testing.T) {
+	var d digest.ForRestore
+	files := map[string]digest.ForRestore{
+		"ks/t1-abc/md-1-big-Data.db":                             d,
+		"ks/t1-abc/manifest.json":                                d,
+		"ks/t2-def/md-2-big-Data.db":                             d,
+		"ks/t2-def/.t2_idx/md-1-big-Data.db":                     d,
+		"other/t3-123/md-1-big-CompressionInfo.db":               d,
+		"new/t4-456/nb-3fw2_0a3k_1x2ph2ljbq59v5pvvw-big-Data.db": d,
+		"new/t4-456/nb-3fw2_0a3k_1x2ph2ljbq59v5pvvw-big-TOC.txt": d,
+	}
+	expected := map[string]map[string]digest.ForRestore{
+		"ks/t1-abc":    {"ks/t1-abc/md-1-big-Data.db": d},
+		"ks/t2-def":    {"ks/t2-def/md-2-big-Data.db": d},
+		"other/t3-123": {"other/t3-123/md-1-big-CompressionInfo.db": d},
+		"new/t4-456": {
+			"new/t4-456/nb-3fw2_0a3k_1x2ph2ljbq59v5pvvw-big-Data.db": d,
+			"new/t4-456/nb-3fw2_0a3k_1x2ph2ljbq59v5pvvw-big-TOC.txt": d,
+		},
+	}
+	grouped, err := groupByTableDirectory(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(grouped, expected); diff != nil {
+		t.Fatal(diff)
+	}
+
+	files["ks/t1-abc/nb-not_a_generation-big-Data.db"] = d
+	if _, err := groupByTableDirectory(files); err != UnrecognizedTableFile {
+		t.Fatalf("expected=%v actual=%v", UnrecognizedTableFile, err)
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"context"
+
+	"github.com/retailnext/cassandrabackup/bucket"
+	"github.com/retailnext/cassandrabackup/digest"
+	"github.com/retailnext/cassandrabackup/manifests"
+	"github.com/retailnext/cassandrabackup/unixtime"
+)
+
+// CommitLogs returns the commitlog segments needed to replay writes from the time of a snapshot
+// up to until, keyed by file name.
+func CommitLogs(ctx context.Context, identity manifests.NodeIdentity, snapshot, until unixtime.Seconds) (map[string]digest.ForRestore, error) {
+	client := bucket.OpenShared()
+	keys, err := client.ListManifests(ctx, identity, snapshot, 0)
+	if err != nil {
+		return nil, err
+	}
+	selected := selectCommitLogs(keys, until)
+	if len(selected) == 0 {
+		return nil, nil
+	}
+	loaded, err := client.GetManifests(ctx, identity, selected)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]digest.ForRestore)
+	for _, m := range loaded {
+		for name, segment := range m.DataFiles {
+			result[name] = segment
+		}
+	}
+	return result, nil
+}
+
+// selectCommitLogs picks the commitlog manifests up to until, plus the first one after it,
+// since a segment is archived some time after the writes in it.
+func selectCommitLogs(keys manifests.ManifestKeys, until unixtime.Seconds) manifests.ManifestKeys {
+	var result manifests.ManifestKeys
+	for _, key := range keys {
+		if key.ManifestType != manifests.ManifestTypeCommitLog {
+			continue
+		}
+		result = append(result, key)
+		if key.Time > until {
+			break
+		}
+	}
+	return result
+}
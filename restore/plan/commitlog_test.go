@@ -0,0 +1,44 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/retailnext/cassandrabackup/manifests"
+)
+
+func TestSelectCommitLogs(t *testing.T) {
+	keys := manifests.ManifestKeys{
+		{Time: 100, ManifestType: manifests.ManifestTypeSnapshot},
+		{Time: 110, ManifestType: manifests.ManifestTypeCommitLog},
+		{Time: 120, ManifestType: manifests.ManifestTypeIncremental},
+		{Time: 130, ManifestType: manifests.ManifestTypeCommitLog},
+		{Time: 140, ManifestType: manifests.ManifestTypeCommitLog},
+		{Time: 150, ManifestType: manifests.ManifestTypeCommitLog},
+	}
+	expected := manifests.ManifestKeys{keys[1], keys[3], keys[4]}
+	if diff := deep.Equal(selectCommitLogs(keys, 135), expected); diff != nil {
+		t.Fatal(diff)
+	}
+	expected = manifests.ManifestKeys{keys[1], keys[3]}
+	if diff := deep.Equal(selectCommitLogs(keys, 125), expected); diff != nil {
+		t.Fatal(diff)
+	}
+	if diff := deep.Equal(selectCommitLogs(keys[:3], 200), manifests.ManifestKeys{keys[1]}); diff != nil {
+		t.Fatal(diff)
+	}
+}
@@ -17,49 +17,25 @@ package plan
 import (
 	"strings"
 
+	"github.com/retailnext/cassandrabackup/manifests"
 	"go.uber.org/zap"
 )
 
 type Filter struct {
-	Tables         map[string]struct{}
+	Tables         manifests.TableFilter
 	IncludeIndexes bool
 }
 
-func (f *Filter) Build(tables []string) {
-	f.Tables = make(map[string]struct{}, len(tables))
-
-	for _, tableSpec := range tables {
-		parts := strings.Split(tableSpec, ".")
-		if len(parts) != 2 {
-			zap.S().Panicw("invalid_table", "table", tableSpec)
-		}
-		f.Tables[tableSpec] = struct{}{}
-	}
-}
-
 func (f Filter) match(name string) bool {
-	parts := strings.Split(name, "/")
-	if len(parts) < 3 {
-		zap.S().Panicw("unexpected_name", "name", name)
+	keyspace, table, _, rest, ok := splitDataPath(name)
+	if !ok {
+		zap.S().Warnw("filter_skipped_unexpected_name", "name", name)
 		return false
 	}
-	if !f.IncludeIndexes {
-		if parts[2] == "" {
-			zap.S().Panicw("unexpected_empty_part", "name", name)
-			return false
-		}
-		if parts[2][0] == '.' {
-			return false
-		}
-	}
-	suffixIndex := strings.LastIndex(parts[1], "-")
-	if suffixIndex < 0 {
-		zap.S().Panicw("unexpected_suffix_index", "name", name)
+	if !f.IncludeIndexes && strings.HasPrefix(rest, ".") && strings.Contains(rest, "/") {
+		return false
 	}
-	keyspace := parts[0]
-	table := parts[1][:suffixIndex]
-	_, ok := f.Tables[keyspace+"."+table]
-	return ok
+	return f.Tables.Match(keyspace, table)
 }
 
 func (p *NodePlan) Filter(f Filter) {
@@ -73,4 +49,9 @@ func (p *NodePlan) Filter(f Filter) {
 			delete(p.ChangedFiles, fileName)
 		}
 	}
+	for fileName := range p.FileInfo {
+		if !f.match(fileName) {
+			delete(p.FileInfo, fileName)
+		}
+	}
 }
@@ -0,0 +1,176 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/retailnext/cassandrabackup/digest"
+	"github.com/retailnext/cassandrabackup/manifests"
+	"github.com/retailnext/cassandrabackup/systemlocal"
+)
+
+// Mapping renames keyspaces and tables when restoring, by rewriting the "keyspace/table-<id>/..."
+// paths of files. Tables that aren't mapped keep their names.
+type Mapping struct {
+	keyspaces map[string]string
+	tables    map[string]string
+	// tableIDs holds the directory suffix to use for target tables, keyed by "keyspace.table".
+	// Tables not in it keep the ID they had when backed up.
+	tableIDs map[string]string
+}
+
+// NewMapping parses specs like "source_ks.table=target_ks.table", or "source_ks=target_ks" to map
+// every table in a keyspace. Table mappings take precedence over keyspace mappings.
+func NewMapping(specs []string) (*Mapping, error) {
+	m := &Mapping{
+		keyspaces: make(map[string]string),
+		tables:    make(map[string]string),
+	}
+	for _, spec := range specs {
+		parts := strings.Split(spec, "=")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid mapping %q: expected source=target", spec)
+		}
+		source, target := parts[0], parts[1]
+		sourceDots, targetDots := strings.Count(source, "."), strings.Count(target, ".")
+		switch {
+		case source == "" || target == "" || sourceDots != targetDots || sourceDots > 1:
+			return nil, fmt.Errorf("invalid mapping %q: expected keyspace=keyspace or keyspace.table=keyspace.table", spec)
+		case sourceDots == 0:
+			m.keyspaces[source] = target
+		default:
+			m.tables[source] = target
+		}
+	}
+	return m, nil
+}
+
+// IsEmpty is true if the mapping wouldn't change any paths.
+func (m *Mapping) IsEmpty() bool {
+	return m == nil || (len(m.keyspaces) == 0 && len(m.tables) == 0 && len(m.tableIDs) == 0)
+}
+
+// LookupTableIDs uses the IDs of the target tables in the cluster that addr belongs to, so that
+// restored files land in the directories Cassandra uses for them. System tables aren't looked up.
+func (m *Mapping) LookupTableIDs(addr string, p NodePlan) error {
+	schema, err := systemlocal.GetSchema(addr)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]string)
+	for _, keyspace := range schema.Keyspaces {
+		for _, table := range keyspace.Tables {
+			existing[keyspace.Name+"."+table.Name] = strings.Replace(table.ID, "-", "", -1)
+		}
+	}
+
+	m.tableIDs = make(map[string]string)
+	for name := range p.Files {
+		keyspace, table, _, _, ok := splitDataPath(name)
+		if !ok || manifests.IsSystemKeyspace(keyspace) {
+			continue
+		}
+		target := m.targetTable(keyspace, table)
+		id, ok := existing[target]
+		if !ok {
+			return fmt.Errorf("table %s does not exist in the target cluster", target)
+		}
+		m.tableIDs[target] = id
+	}
+	return nil
+}
+
+func (m *Mapping) targetTable(keyspace, table string) string {
+	if target, ok := m.tables[keyspace+"."+table]; ok {
+		return target
+	}
+	if target, ok := m.keyspaces[keyspace]; ok {
+		return target + "." + table
+	}
+	return keyspace + "." + table
+}
+
+// Rename returns the path a backed-up file should be restored to.
+func (m *Mapping) Rename(name string) string {
+	keyspace, table, id, rest, ok := splitDataPath(name)
+	if !ok {
+		return name
+	}
+	target := m.targetTable(keyspace, table)
+	if targetID, ok := m.tableIDs[target]; ok {
+		id = targetID
+	}
+	i := strings.Index(target, ".")
+	return target[:i] + "/" + target[i+1:] + "-" + id + "/" + rest
+}
+
+// splitDataPath splits "keyspace/table-<id>/rest".
+func splitDataPath(name string) (keyspace, table, id, rest string, ok bool) {
+	parts := strings.SplitN(name, "/", 3)
+	if len(parts) != 3 {
+		return "", "", "", "", false
+	}
+	i := strings.LastIndex(parts[1], "-")
+	if i <= 0 {
+		return "", "", "", "", false
+	}
+	return parts[0], parts[1][:i], parts[1][i+1:], parts[2], true
+}
+
+// Remap renames the plan's files. It fails if two files would be restored to the same path.
+func (p *NodePlan) Remap(m *Mapping) error {
+	if m.IsEmpty() {
+		return nil
+	}
+	files := make(map[string]digest.ForRestore, len(p.Files))
+	sources := make(map[string]string, len(p.Files))
+	for _, name := range sortedFileNames(p.Files) {
+		renamed := m.Rename(name)
+		if other, ok := sources[renamed]; ok {
+			return fmt.Errorf("mapping restores both %s and %s to %s", other, name, renamed)
+		}
+		sources[renamed] = name
+		files[renamed] = p.Files[name]
+	}
+	p.Files = files
+
+	if p.ChangedFiles != nil {
+		changedFiles := make(map[string][]HistoryEntry, len(p.ChangedFiles))
+		for name, history := range p.ChangedFiles {
+			changedFiles[m.Rename(name)] = history
+		}
+		p.ChangedFiles = changedFiles
+	}
+	if p.FileInfo != nil {
+		fileInfo := make(map[string]manifests.FileInfo, len(p.FileInfo))
+		for name, info := range p.FileInfo {
+			fileInfo[m.Rename(name)] = info
+		}
+		p.FileInfo = fileInfo
+	}
+	return nil
+}
+
+func sortedFileNames(files map[string]digest.ForRestore) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
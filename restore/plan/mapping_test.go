@@ -0,0 +1,93 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/retailnext/cassandrabackup/digest"
+	"github.com/retailnext/cassandrabackup/manifests"
+)
+
+func TestMappingRename(t *testing.T) {
+	m, err := NewMapping([]string{"prod_ks=staging_ks", "prod_ks.events=staging_ks.events_copy", "other.a=other.b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.tableIDs = map[string]string{"staging_ks.users": "ffff"}
+
+	for name, expected := range map[string]string{
+		"prod_ks/events-1234/md-1-big-Data.db":           "staging_ks/events_copy-1234/md-1-big-Data.db",
+		"prod_ks/users-1234/md-1-big-Data.db":            "staging_ks/users-ffff/md-1-big-Data.db",
+		"prod_ks/users-1234/.users_idx/md-1-big-TOC.txt": "staging_ks/users-ffff/.users_idx/md-1-big-TOC.txt",
+		"other/a-5678/md-2-big-Data.db":                  "other/b-5678/md-2-big-Data.db",
+		"other/c-5678/md-2-big-Data.db":                  "other/c-5678/md-2-big-Data.db",
+		"unexpected":                                     "unexpected",
+	} {
+		if actual := m.Rename(name); actual != expected {
+			t.Errorf("Rename(%q)=%q expected %q", name, actual, expected)
+		}
+	}
+}
+
+func TestNewMappingErrors(t *testing.T) {
+	for _, spec := range []string{"a", "a=b=c", "a.b=c", "a=", "a.b.c=d.e.f"} {
+		if _, err := NewMapping([]string{spec}); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
+
+func TestRemap(t *testing.T) {
+	var d digest.ForRestore
+	p := NodePlan{
+		Files: map[string]digest.ForRestore{
+			"prod_ks/events-1234/md-1-big-Data.db": d,
+			"other/t-5678/md-1-big-Data.db":        d,
+		},
+		FileInfo: map[string]manifests.FileInfo{
+			"prod_ks/events-1234/md-1-big-Data.db": {Length: 1},
+		},
+	}
+	m, err := NewMapping([]string{"prod_ks=staging_ks"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Remap(m); err != nil {
+		t.Fatal(err)
+	}
+	expected := NodePlan{
+		Files: map[string]digest.ForRestore{
+			"staging_ks/events-1234/md-1-big-Data.db": d,
+			"other/t-5678/md-1-big-Data.db":           d,
+		},
+		FileInfo: map[string]manifests.FileInfo{
+			"staging_ks/events-1234/md-1-big-Data.db": {Length: 1},
+		},
+	}
+	if diff := deep.Equal(p, expected); diff != nil {
+		t.Fatal(diff)
+	}
+
+	m, err = NewMapping([]string{"staging_ks.events=other.t"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.tableIDs = map[string]string{"other.t": "5678"}
+	if err := p.Remap(m); err == nil {
+		t.Fatal("expected collision error")
+	}
+}
@@ -30,9 +30,17 @@ type HistoryEntry struct {
 }
 
 type NodePlan struct {
-	Files             map[string]digest.ForRestore
+	Files map[string]digest.ForRestore
+	// FileInfo has entries for the Files whose manifest recorded them.
+	FileInfo          map[string]manifests.FileInfo
 	ChangedFiles      map[string][]HistoryEntry
 	SelectedManifests manifests.ManifestKeys
+	// ReleaseVersion is the newest Cassandra version recorded by the selected manifests, if any.
+	ReleaseVersion string
+	// Tokens are the tokens recorded by the newest selected manifest that has any.
+	Tokens []string
+	// ExcludedTables are the table patterns that any selected manifest left out of its backup.
+	ExcludedTables []string
 }
 
 func Create(ctx context.Context, identity manifests.NodeIdentity, startAfter, notAfter unixtime.Seconds) (NodePlan, error) {
@@ -54,6 +62,13 @@ func getManifests(ctx context.Context, identity manifests.NodeIdentity, startAft
 	if err != nil {
 		return nil, err
 	}
+	dataKeys := keys[:0]
+	for _, key := range keys {
+		if key.ManifestType != manifests.ManifestTypeCommitLog {
+			dataKeys = append(dataKeys, key)
+		}
+	}
+	keys = dataKeys
 
 	snapshotIndex := -1
 	for i := len(keys) - 1; i >= 0; i-- {
@@ -78,10 +93,29 @@ func assemble(nodeManifests []manifests.Manifest) NodePlan {
 	}
 
 	fileHistories := make(map[string][]HistoryEntry)
+	fileInfo := make(map[string]manifests.FileInfo)
+	excluded := make(map[string]struct{})
 	for _, manifest := range nodeManifests {
 		nodePlan.SelectedManifests = append(nodePlan.SelectedManifests, manifest.Key())
+		if manifest.ReleaseVersion != "" {
+			nodePlan.ReleaseVersion = manifest.ReleaseVersion
+		}
+		if len(manifest.Tokens) > 0 {
+			nodePlan.Tokens = manifest.Tokens
+		}
+		for _, pattern := range manifest.ExcludedTables {
+			if _, ok := excluded[pattern]; !ok {
+				excluded[pattern] = struct{}{}
+				nodePlan.ExcludedTables = append(nodePlan.ExcludedTables, pattern)
+			}
+		}
 
 		for name, file := range manifest.DataFiles {
+			if info, ok := manifest.DataFileInfo[name]; ok {
+				fileInfo[name] = info
+			} else {
+				delete(fileInfo, name)
+			}
 			history := fileHistories[name]
 			entry := HistoryEntry{
 				Manifest: manifest.Key(),
@@ -91,6 +125,10 @@ func assemble(nodeManifests []manifests.Manifest) NodePlan {
 		}
 	}
 
+	if len(fileInfo) > 0 {
+		nodePlan.FileInfo = fileInfo
+	}
+
 	if len(fileHistories) > 0 {
 		nodePlan.Files = make(map[string]digest.ForRestore, len(fileHistories))
 		for name, history := range fileHistories {
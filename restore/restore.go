@@ -28,6 +28,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/retailnext/cassandrabackup/bucket"
 	"github.com/retailnext/cassandrabackup/digest"
+	"github.com/retailnext/cassandrabackup/manifests"
 	"github.com/retailnext/cassandrabackup/paranoid"
 	"github.com/retailnext/cassandrabackup/restore/plan"
 	"github.com/retailnext/cassandrabackup/writefile"
@@ -39,6 +40,8 @@ type worker struct {
 	cache  *digest.Cache
 	client *bucket.Client
 	target writefile.Config
+	// fileInfo is set when restored files should get their original modification times.
+	fileInfo map[string]manifests.FileInfo
 
 	limiter    chan struct{}
 	wg         sync.WaitGroup
@@ -134,6 +137,7 @@ func (w *worker) restoreFile(name string, forRestore digest.ForRestore) {
 			if forUpload.ForRestore() == forRestore {
 				skippedBytes.Add(float64(maybeFile.Len()))
 				skippedFiles.Inc()
+				err = w.restoreModTime(name, path)
 				return
 			} else {
 				lgr.Infow("existing_file_digest_mismatch", "path", path)
@@ -154,6 +158,9 @@ func (w *worker) restoreFile(name string, forRestore digest.ForRestore) {
 		d := time.Since(start)
 		downloadFiles.Inc()
 		downloadSeconds.Add(d.Seconds())
+		if modTimeErr := w.restoreModTime(name, file.Name()); modTimeErr != nil {
+			return modTimeErr
+		}
 		if info, infoErr := file.Stat(); infoErr != nil {
 			lgr.Warnw("stat_error", "err", err)
 		} else {
@@ -170,6 +177,15 @@ func (w *worker) restoreFile(name string, forRestore digest.ForRestore) {
 	}
 }
 
+// restoreModTime sets the modification time of the file at path to the one recorded for name, if any.
+func (w *worker) restoreModTime(name, path string) error {
+	info, ok := w.fileInfo[name]
+	if !ok || info.ModTimeNanos == 0 {
+		return nil
+	}
+	return os.Chtimes(path, time.Now(), info.ModTime())
+}
+
 var (
 	skippedFiles = prometheus.NewCounter(prometheus.CounterOpts{
 		Namespace: "cassandrabackup",
@@ -0,0 +1,177 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package restore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"syscall"
+
+	"github.com/retailnext/cassandrabackup/bucket"
+	"github.com/retailnext/cassandrabackup/cassandraconfig"
+	"github.com/retailnext/cassandrabackup/manifests"
+	"github.com/retailnext/cassandrabackup/restore/plan"
+	"go.uber.org/zap"
+)
+
+var CassandraRunning = errors.New("cassandra is running")
+var ClusterNameMismatch = errors.New("cluster_name does not match the backup")
+var TokenMismatch = errors.New("initial_token does not match the backup")
+var InsufficientDiskSpace = errors.New("not enough free disk space for restore")
+
+const cassandraDaemonClass = "org.apache.cassandra.service.CassandraDaemon"
+
+type safetyChecks struct {
+	allowRunning  bool
+	allowCluster  bool
+	allowTokens   bool
+	allowLowSpace bool
+}
+
+// checkSafety refuses to restore over a node that is running or doesn't look like the one that was
+// backed up, unless the specific check has been overridden.
+func checkSafety(ctx context.Context, identity manifests.NodeIdentity, nodePlan plan.NodePlan, checks safetyChecks) error {
+	lgr := zap.S()
+
+	running, err := cassandraRunning("/proc")
+	if err != nil {
+		lgr.Warnw("cassandra_running_unknown", "err", err)
+	} else if running {
+		if !checks.allowRunning {
+			return CassandraRunning
+		}
+		lgr.Warnw("restoring_while_cassandra_running")
+	}
+
+	cfg, err := cassandraconfig.Load()
+	if err != nil {
+		return err
+	}
+	if cfg.ClusterName != identity.Cluster {
+		lgr.Warnw("cluster_name_mismatch", "config", cfg.ClusterName, "backup", identity.Cluster)
+		if !checks.allowCluster {
+			return ClusterNameMismatch
+		}
+	}
+	if !sameTokens(cfg.Tokens(), nodePlan.Tokens) {
+		lgr.Warnw("initial_token_mismatch", "config", cfg.Tokens(), "backup", nodePlan.Tokens)
+		if !checks.allowTokens {
+			return TokenMismatch
+		}
+	}
+
+	needed, err := plannedBytes(ctx, bucket.OpenShared(), dataDirectory, nodePlan)
+	if err != nil {
+		return err
+	}
+	free, err := freeBytes(dataDirectory)
+	if err != nil {
+		return err
+	}
+	if free < needed {
+		lgr.Warnw("insufficient_disk_space", "directory", dataDirectory, "free", free, "needed", needed)
+		if !checks.allowLowSpace {
+			return InsufficientDiskSpace
+		}
+	}
+	return nil
+}
+
+// cassandraRunning looks for a process running the Cassandra daemon class.
+func cassandraRunning(procDirectory string) (bool, error) {
+	entries, err := ioutil.ReadDir(procDirectory)
+	if err != nil {
+		return false, err
+	}
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil {
+			continue
+		}
+		cmdline, err := ioutil.ReadFile(filepath.Join(procDirectory, entry.Name(), "cmdline"))
+		if err != nil {
+			// Processes come and go, and some aren't ours to read.
+			continue
+		}
+		for _, arg := range bytes.Split(cmdline, []byte{0}) {
+			if string(arg) == cassandraDaemonClass {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func sameTokens(configured, backedUp []string) bool {
+	if len(configured) != len(backedUp) {
+		return false
+	}
+	sorted := append([]string(nil), backedUp...)
+	sort.Strings(sorted)
+	for i := range sorted {
+		if configured[i] != sorted[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// plannedBytes estimates how much the restore will write, not counting files that are already
+// in place with the expected size.
+func plannedBytes(ctx context.Context, client *bucket.Client, directory string, nodePlan plan.NodePlan) (int64, error) {
+	var total int64
+	for name, file := range nodePlan.Files {
+		var length int64
+		if info, ok := nodePlan.FileInfo[name]; ok {
+			length = info.Length
+		} else {
+			blobInfo, err := client.StatBlob(ctx, file)
+			if err != nil {
+				return 0, err
+			}
+			length = blobInfo.ContentLength
+		}
+		if existing, err := os.Stat(filepath.Join(directory, name)); err == nil && existing.Size() == length {
+			continue
+		}
+		total += length
+	}
+	return total, nil
+}
+
+// freeBytes returns the space available to unprivileged users on the filesystem that holds
+// path, or would hold it once created.
+func freeBytes(path string) (int64, error) {
+	for {
+		var stat syscall.Statfs_t
+		err := syscall.Statfs(path, &stat)
+		if err == nil {
+			return int64(stat.Bavail) * int64(stat.Bsize), nil
+		}
+		if !os.IsNotExist(err) {
+			return 0, err
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return 0, err
+		}
+		path = parent
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package restore
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/retailnext/cassandrabackup/digest"
+	"github.com/retailnext/cassandrabackup/manifests"
+	"github.com/retailnext/cassandrabackup/restore/plan"
+)
+
+func TestCassandraRunning(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	addProcess := func(pid string, args ...string) {
+		if err := os.Mkdir(filepath.Join(dir, pid), 0755); err != nil {
+			t.Fatal(err)
+		}
+		var cmdline []byte
+		for _, arg := range args {
+			cmdline = append(cmdline, arg...)
+			cmdline = append(cmdline, 0)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, pid, "cmdline"), cmdline, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	addProcess("1", "/sbin/init")
+	addProcess("self", "java", cassandraDaemonClass)
+	addProcess("20", "grep", "org.apache.cassandra.service.CassandraDaemon-foo")
+
+	if running, err := cassandraRunning(dir); err != nil || running {
+		t.Fatalf("running=%v err=%v", running, err)
+	}
+	addProcess("30", "java", "-Xmx8G", "-cp", "/usr/share/cassandra/*", cassandraDaemonClass)
+	if running, err := cassandraRunning(dir); err != nil || !running {
+		t.Fatalf("running=%v err=%v", running, err)
+	}
+}
+
+func TestSameTokens(t *testing.T) {
+	if !sameTokens([]string{"-1", "10", "5"}, []string{"5", "-1", "10"}) {
+		t.Fatal("expected tokens to match")
+	}
+	if sameTokens(nil, []string{"5"}) {
+		t.Fatal("expected empty initial_token not to match")
+	}
+	if sameTokens([]string{"-1", "6"}, []string{"-1", "5"}) {
+		t.Fatal("expected different tokens not to match")
+	}
+}
+
+func TestPlannedBytes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	existing := "ks/t-1234/md-1-big-Data.db"
+	if err := os.MkdirAll(filepath.Join(dir, "ks/t-1234"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, existing), make([]byte, 100), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	nodePlan := plan.NodePlan{
+		Files: map[string]digest.ForRestore{
+			existing:                      {},
+			"ks/t-1234/md-2-big-Data.db":  {},
+			"ks/t-1234/md-1-big-Index.db": {},
+		},
+		FileInfo: map[string]manifests.FileInfo{
+			existing:                      {Length: 100},
+			"ks/t-1234/md-2-big-Data.db":  {Length: 1000},
+			"ks/t-1234/md-1-big-Index.db": {Length: 10},
+		},
+	}
+	needed, err := plannedBytes(context.Background(), nil, dir, nodePlan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if needed != 1010 {
+		t.Fatalf("expected=1010 actual=%d", needed)
+	}
+
+	free, err := freeBytes(filepath.Join(dir, "not", "created", "yet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if free <= 0 {
+		t.Fatalf("expected free space, got %d", free)
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package restore
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+
+	"github.com/retailnext/cassandrabackup/bucket"
+	"github.com/retailnext/cassandrabackup/nodeidentity"
+	"github.com/retailnext/cassandrabackup/schema"
+	"github.com/retailnext/cassandrabackup/unixtime"
+	"go.uber.org/zap"
+)
+
+func RestoreSchema(ctx context.Context) error {
+	identity := nodeidentity.ForRestore(ctx, schemaCmdCluster, schemaCmdHostname, schemaCmdHostnamePattern)
+	lgr := zap.S().With("identity", identity)
+
+	manifest, script, err := schema.Latest(ctx, bucket.OpenShared(), identity, unixtime.Seconds(*schemaCmdNotAfter))
+	if err != nil {
+		return err
+	}
+	lgr.Infow("selected_schema", "manifest", manifest.Key(), "schema", manifest.Schema)
+
+	if *schemaCmdOutputFile == "-" {
+		_, err = os.Stdout.Write(script)
+		return err
+	}
+	return ioutil.WriteFile(*schemaCmdOutputFile, script, 0644)
+}
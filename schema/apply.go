@@ -0,0 +1,172 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/retailnext/cassandrabackup/bucket"
+	"github.com/retailnext/cassandrabackup/manifests"
+	"github.com/retailnext/cassandrabackup/nodeidentity"
+	"github.com/retailnext/cassandrabackup/systemlocal"
+	"github.com/retailnext/cassandrabackup/unixtime"
+	"go.uber.org/zap"
+)
+
+func Apply(ctx context.Context) error {
+	identity := nodeidentity.ForRestore(ctx, applyCmdCluster, applyCmdHostname, applyCmdHostnamePattern)
+	lgr := zap.S().With("identity", identity)
+
+	manifest, script, err := Latest(ctx, bucket.OpenShared(), identity, unixtime.Seconds(*applyCmdNotAfter))
+	if err != nil {
+		return err
+	}
+	lgr.Infow("selected_schema", "manifest", manifest.Key(), "schema", manifest.Schema)
+
+	statements, err := Parse(string(script))
+	if err != nil {
+		return err
+	}
+	statements, err = overrideReplication(statements, *applyCmdReplication, *applyCmdRenameDC)
+	if err != nil {
+		return err
+	}
+
+	existing, err := systemlocal.GetSchema(*applyCmdTarget)
+	if err != nil {
+		return err
+	}
+	statements = skipExisting(statements, existing)
+
+	if *applyCmdDryRun {
+		for _, statement := range statements {
+			if _, err := fmt.Fprintf(os.Stdout, "%s;\n\n", statement.CQL); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	session, err := systemlocal.NewSession(*applyCmdTarget)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+	for _, statement := range statements {
+		if err := session.Query(statement.CQL).WithContext(ctx).Exec(); err != nil {
+			lgr.Errorw("schema_apply_error", "statement", statement.String(), "err", err)
+			return err
+		}
+		lgr.Infow("schema_created", "statement", statement.String())
+	}
+	return nil
+}
+
+// overrideReplication rewrites CREATE KEYSPACE statements. Datacenters are renamed within
+// the backed-up replication options, unless they're replaced by an override for the keyspace.
+func overrideReplication(statements []Statement, overrides, renamedDCs map[string]string) ([]Statement, error) {
+	lgr := zap.S()
+	parsedOverrides := make(map[string]map[string]string, len(overrides))
+	for keyspace, literal := range overrides {
+		trimmed := strings.TrimSpace(literal)
+		if !strings.HasPrefix(trimmed, "{") || !strings.HasSuffix(trimmed, "}") {
+			return nil, fmt.Errorf("replication for %s must be a map: %q", keyspace, literal)
+		}
+		replication, err := parseMap(trimmed[1 : len(trimmed)-1])
+		if err != nil {
+			return nil, err
+		}
+		parsedOverrides[keyspace] = replication
+	}
+
+	result := make([]Statement, 0, len(statements))
+	for _, statement := range statements {
+		if statement.Kind != KindKeyspace {
+			result = append(result, statement)
+			continue
+		}
+		replication, ok := parsedOverrides[statement.Keyspace]
+		if ok {
+			delete(parsedOverrides, statement.Keyspace)
+		} else if len(renamedDCs) > 0 {
+			original, err := statement.Replication()
+			if err != nil {
+				return nil, err
+			}
+			replication = make(map[string]string, len(original))
+			for option, value := range original {
+				if renamed, ok := renamedDCs[option]; ok && option != "class" && option != "replication_factor" {
+					option = renamed
+				}
+				replication[option] = value
+			}
+		} else {
+			result = append(result, statement)
+			continue
+		}
+		rewritten, err := statement.WithReplication(replication)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, rewritten)
+	}
+	for keyspace := range parsedOverrides {
+		lgr.Warnw("replication_override_unused", "keyspace", keyspace)
+	}
+	return result, nil
+}
+
+// skipExisting drops statements that would create keyspaces, types, tables or indexes that already exist.
+// Tables that already exist aren't altered either.
+func skipExisting(statements []Statement, existing systemlocal.Schema) []Statement {
+	lgr := zap.S()
+	exists := make(map[string]struct{})
+	add := func(kind, keyspace, name string) {
+		exists[Statement{Kind: kind, Keyspace: keyspace, Name: name}.String()] = struct{}{}
+	}
+	for _, keyspace := range existing.Keyspaces {
+		add(KindKeyspace, keyspace.Name, "")
+		for _, userType := range keyspace.Types {
+			add(KindType, keyspace.Name, userType.Name)
+		}
+		for _, table := range keyspace.Tables {
+			add(KindTable, keyspace.Name, table.Name)
+			for _, index := range table.Indexes {
+				add(KindIndex, keyspace.Name, index.Name)
+			}
+		}
+	}
+
+	result := make([]Statement, 0, len(statements))
+	for _, statement := range statements {
+		target := statement
+		if target.Kind == KindAlter {
+			target.Kind = KindTable
+		}
+		if _, ok := exists[target.String()]; ok {
+			lgr.Infow("schema_skipped_existing", "statement", statement.String())
+			continue
+		}
+		if manifests.IsSystemKeyspace(statement.Keyspace) {
+			lgr.Infow("schema_skipped_system", "statement", statement.String())
+			continue
+		}
+		result = append(result, statement)
+	}
+	return result
+}
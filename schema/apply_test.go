@@ -0,0 +1,86 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/retailnext/cassandrabackup/systemlocal"
+)
+
+func TestOverrideReplication(t *testing.T) {
+	statements, err := Parse(testScript + `CREATE KEYSPACE other WITH replication = {'class': 'NetworkTopologyStrategy', 'us-east': '3', 'us-west': '2'};`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	overridden, err := overrideReplication(statements, map[string]string{
+		"Events":  "{'class': 'SimpleStrategy', 'replication_factor': '1'}",
+		"missing": "{}",
+	}, map[string]string{
+		"us-east": "dc1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(overridden[1:5], statements[1:5]); diff != nil {
+		t.Fatal(diff)
+	}
+	var replications []map[string]string
+	for _, i := range []int{0, 5} {
+		replication, err := overridden[i].Replication()
+		if err != nil {
+			t.Fatal(err)
+		}
+		replications = append(replications, replication)
+	}
+	expected := []map[string]string{
+		{"class": "SimpleStrategy", "replication_factor": "1"},
+		{"class": "NetworkTopologyStrategy", "dc1": "3", "us-west": "2"},
+	}
+	if diff := deep.Equal(replications, expected); diff != nil {
+		t.Fatal(diff)
+	}
+
+	if _, err := overrideReplication(statements, map[string]string{"Events": "SimpleStrategy"}, nil); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestSkipExisting(t *testing.T) {
+	statements, err := Parse(testScript + `CREATE TABLE system_auth.roles (role text PRIMARY KEY);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	existing := systemlocal.Schema{
+		Keyspaces: []systemlocal.Keyspace{
+			{
+				Name:  "Events",
+				Types: []systemlocal.UserType{{Name: "address"}},
+			},
+		},
+	}
+	remaining := skipExisting(statements, existing)
+	if diff := deep.Equal(remaining, statements[2:5]); diff != nil {
+		t.Fatal(diff)
+	}
+
+	// A table that already exists has its own dropped columns.
+	existing.Keyspaces[0].Tables = []systemlocal.Table{{Name: "by_day"}}
+	remaining = skipExisting(statements, existing)
+	if diff := deep.Equal(remaining, statements[3:4]); diff != nil {
+		t.Fatal(diff)
+	}
+}
@@ -0,0 +1,31 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import "gopkg.in/alecthomas/kingpin.v2"
+
+var (
+	Cmd      = kingpin.Command("schema", "")
+	ApplyCmd = Cmd.Command("apply", "Create a backed-up schema in a target cluster, skipping objects that already exist")
+
+	applyCmdTarget          = ApplyCmd.Flag("target", "Address of a node in the cluster to create the schema in.").Required().String()
+	applyCmdNotAfter        = ApplyCmd.Flag("not-after", "Ignore snapshots after this time (unix seconds)").Int64()
+	applyCmdCluster         = ApplyCmd.Flag("cluster", "Use a different cluster name when selecting a backup.").String()
+	applyCmdHostname        = ApplyCmd.Flag("hostname", "Use a specific hostname when selecting a backup.").String()
+	applyCmdHostnamePattern = ApplyCmd.Flag("hostname-pattern", "Use a prefix pattern when selecting a backup.").String()
+	applyCmdReplication     = ApplyCmd.Flag("replication", "Replace a keyspace's replication, e.g. ks={'class': 'SimpleStrategy', 'replication_factor': '1'}").PlaceHolder("KEYSPACE=MAP").StringMap()
+	applyCmdRenameDC        = ApplyCmd.Flag("rename-dc", "Use a different datacenter name in keyspace replication.").PlaceHolder("OLD=NEW").StringMap()
+	applyCmdDryRun          = ApplyCmd.Flag("dry-run", "Print the statements that would be executed instead of executing them").Bool()
+)
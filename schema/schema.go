@@ -0,0 +1,52 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"context"
+	"errors"
+
+	"github.com/retailnext/cassandrabackup/bucket"
+	"github.com/retailnext/cassandrabackup/manifests"
+	"github.com/retailnext/cassandrabackup/unixtime"
+)
+
+var NoSchemaFound = errors.New("no snapshots with a schema found for host")
+
+// Latest returns the newest snapshot manifest not after notAfter that has a schema, and that schema.
+func Latest(ctx context.Context, client *bucket.Client, identity manifests.NodeIdentity, notAfter unixtime.Seconds) (manifests.Manifest, []byte, error) {
+	keys, err := client.ListManifests(ctx, identity, 0, notAfter)
+	if err != nil {
+		return manifests.Manifest{}, nil, err
+	}
+	for i := len(keys) - 1; i >= 0; i-- {
+		if keys[i].ManifestType != manifests.ManifestTypeSnapshot {
+			continue
+		}
+		loaded, err := client.GetManifests(ctx, identity, keys[i:i+1])
+		if err != nil {
+			return manifests.Manifest{}, nil, err
+		}
+		if loaded[0].Schema == "" {
+			continue
+		}
+		schema, err := client.GetSchema(ctx, loaded[0].Schema)
+		if err != nil {
+			return manifests.Manifest{}, nil, err
+		}
+		return loaded[0], schema, nil
+	}
+	return manifests.Manifest{}, nil, NoSchemaFound
+}
@@ -0,0 +1,286 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/retailnext/cassandrabackup/systemlocal"
+)
+
+var UnterminatedQuote = errors.New("unterminated quoted string or identifier")
+
+const (
+	KindKeyspace = "KEYSPACE"
+	KindType     = "TYPE"
+	KindTable    = "TABLE"
+	KindIndex    = "INDEX"
+	// KindAlter statements change a table created earlier in the script. Schemas only use them
+	// to recreate dropped columns.
+	KindAlter = "ALTER"
+)
+
+// Statement is one CREATE or ALTER TABLE statement from a schema script.
+type Statement struct {
+	Kind     string
+	Keyspace string
+	// Name is the name of the type, table or index. It is empty for keyspaces.
+	Name string
+	CQL  string
+}
+
+func (s Statement) String() string {
+	if s.Name == "" {
+		return s.Kind + " " + s.Keyspace
+	}
+	return s.Kind + " " + s.Keyspace + "." + s.Name
+}
+
+const identifierPattern = `("(?:[^"]|"")*"|[A-Za-z0-9_]+)`
+
+var (
+	createExpr = regexp.MustCompile(`(?is)^CREATE\s+(?:CUSTOM\s+)?(KEYSPACE|TYPE|TABLE|COLUMNFAMILY|INDEX)\s+(?:IF\s+NOT\s+EXISTS\s+)?` +
+		identifierPattern + `(?:\s*\.\s*` + identifierPattern + `)?`)
+	alterExpr = regexp.MustCompile(`(?is)^ALTER\s+(?:TABLE|COLUMNFAMILY)\s+` +
+		identifierPattern + `(?:\s*\.\s*` + identifierPattern + `)?`)
+	indexTableExpr  = regexp.MustCompile(`(?is)\sON\s+` + identifierPattern + `\s*\.\s*` + identifierPattern)
+	replicationExpr = regexp.MustCompile(`(?i)\breplication\s*=\s*\{`)
+)
+
+// Parse splits a schema script into statements.
+func Parse(script string) ([]Statement, error) {
+	var result []Statement
+	start := 0
+	for i := 0; i < len(script); i++ {
+		switch script[i] {
+		case '\'', '"':
+			end, err := skipQuoted(script, i)
+			if err != nil {
+				return nil, err
+			}
+			i = end - 1
+		case ';':
+			if statement := strings.TrimSpace(script[start:i]); statement != "" {
+				parsed, err := parseStatement(statement)
+				if err != nil {
+					return nil, err
+				}
+				result = append(result, parsed)
+			}
+			start = i + 1
+		}
+	}
+	if statement := strings.TrimSpace(script[start:]); statement != "" {
+		parsed, err := parseStatement(statement)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, parsed)
+	}
+	return result, nil
+}
+
+func parseStatement(cql string) (Statement, error) {
+	if match := alterExpr.FindStringSubmatch(cql); match != nil {
+		if match[2] == "" {
+			return Statement{}, fmt.Errorf("name must be qualified with its keyspace: %q", firstLine(cql))
+		}
+		return Statement{
+			Kind:     KindAlter,
+			Keyspace: unquoteIdentifier(match[1]),
+			Name:     unquoteIdentifier(match[2]),
+			CQL:      cql,
+		}, nil
+	}
+
+	match := createExpr.FindStringSubmatch(cql)
+	if match == nil {
+		return Statement{}, fmt.Errorf("not a CREATE KEYSPACE, TYPE, TABLE, INDEX or ALTER TABLE statement: %q", firstLine(cql))
+	}
+	result := Statement{
+		Kind: strings.ToUpper(match[1]),
+		CQL:  cql,
+	}
+	if result.Kind == "COLUMNFAMILY" {
+		result.Kind = KindTable
+	}
+	qualified := match[3] != ""
+
+	switch result.Kind {
+	case KindKeyspace:
+		if qualified {
+			return Statement{}, fmt.Errorf("invalid keyspace name: %q", firstLine(cql))
+		}
+		result.Keyspace = unquoteIdentifier(match[2])
+	case KindIndex:
+		if qualified {
+			result.Name = unquoteIdentifier(match[3])
+		} else {
+			result.Name = unquoteIdentifier(match[2])
+		}
+		on := indexTableExpr.FindStringSubmatch(cql)
+		if on == nil {
+			return Statement{}, fmt.Errorf("index must be on a table qualified with its keyspace: %q", firstLine(cql))
+		}
+		result.Keyspace = unquoteIdentifier(on[1])
+	default:
+		if !qualified {
+			return Statement{}, fmt.Errorf("name must be qualified with its keyspace: %q", firstLine(cql))
+		}
+		result.Keyspace = unquoteIdentifier(match[2])
+		result.Name = unquoteIdentifier(match[3])
+	}
+	return result, nil
+}
+
+// Replication returns the replication options of a CREATE KEYSPACE statement.
+func (s Statement) Replication() (map[string]string, error) {
+	start, end, err := s.replicationSpan()
+	if err != nil {
+		return nil, err
+	}
+	return parseMap(s.CQL[start+1 : end-1])
+}
+
+// WithReplication returns a copy of a CREATE KEYSPACE statement that uses different replication options.
+func (s Statement) WithReplication(replication map[string]string) (Statement, error) {
+	start, end, err := s.replicationSpan()
+	if err != nil {
+		return Statement{}, err
+	}
+	s.CQL = s.CQL[:start] + systemlocal.QuoteMap(replication) + s.CQL[end:]
+	return s, nil
+}
+
+// replicationSpan finds the braces of the replication map, including them.
+func (s Statement) replicationSpan() (int, int, error) {
+	if s.Kind != KindKeyspace {
+		return 0, 0, fmt.Errorf("%s has no replication", s)
+	}
+	loc := replicationExpr.FindStringIndex(s.CQL)
+	if loc == nil {
+		return 0, 0, fmt.Errorf("%s has no replication", s)
+	}
+	start := loc[1] - 1
+	for i := loc[1]; i < len(s.CQL); i++ {
+		switch s.CQL[i] {
+		case '\'', '"':
+			end, err := skipQuoted(s.CQL, i)
+			if err != nil {
+				return 0, 0, err
+			}
+			i = end - 1
+		case '}':
+			return start, i + 1, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("%s has an unterminated replication map", s)
+}
+
+// parseMap parses the inside of a CQL map literal such as 'class': 'SimpleStrategy', 'replication_factor': 3.
+func parseMap(literal string) (map[string]string, error) {
+	result := make(map[string]string)
+	i := 0
+	next := func() (string, error) {
+		for i < len(literal) && strings.ContainsRune(" \t\r\n", rune(literal[i])) {
+			i++
+		}
+		if i >= len(literal) {
+			return "", fmt.Errorf("incomplete map: %q", literal)
+		}
+		if literal[i] == '\'' {
+			end, err := skipQuoted(literal, i)
+			if err != nil {
+				return "", err
+			}
+			value := strings.Replace(literal[i+1:end-1], "''", "'", -1)
+			i = end
+			return value, nil
+		}
+		start := i
+		for i < len(literal) && !strings.ContainsRune(":, \t\r\n", rune(literal[i])) {
+			i++
+		}
+		return literal[start:i], nil
+	}
+	expect := func(c byte) error {
+		for i < len(literal) && strings.ContainsRune(" \t\r\n", rune(literal[i])) {
+			i++
+		}
+		if i >= len(literal) || literal[i] != c {
+			return fmt.Errorf("expected %q in map: %q", c, literal)
+		}
+		i++
+		return nil
+	}
+
+	if strings.TrimSpace(literal) == "" {
+		return result, nil
+	}
+	for {
+		key, err := next()
+		if err != nil {
+			return nil, err
+		}
+		if err := expect(':'); err != nil {
+			return nil, err
+		}
+		value, err := next()
+		if err != nil {
+			return nil, err
+		}
+		result[key] = value
+		if strings.TrimSpace(literal[i:]) == "" {
+			return result, nil
+		}
+		if err := expect(','); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// skipQuoted returns the index just after the quoted string or identifier starting at i.
+// Quotes are escaped by doubling them.
+func skipQuoted(s string, i int) (int, error) {
+	quote := s[i]
+	for j := i + 1; j < len(s); j++ {
+		if s[j] != quote {
+			continue
+		}
+		if j+1 < len(s) && s[j+1] == quote {
+			j++
+			continue
+		}
+		return j + 1, nil
+	}
+	return 0, UnterminatedQuote
+}
+
+func unquoteIdentifier(identifier string) string {
+	if strings.HasPrefix(identifier, `"`) {
+		return strings.Replace(identifier[1:len(identifier)-1], `""`, `"`, -1)
+	}
+	return strings.ToLower(identifier)
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
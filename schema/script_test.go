@@ -0,0 +1,122 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+const testScript = `CREATE KEYSPACE "Events" WITH replication = {'class': 'org.apache.cassandra.locator.NetworkTopologyStrategy', 'us-east': '3'} AND durable_writes = true;
+
+CREATE TYPE "Events".address (
+    street text
+);
+
+CREATE TABLE "Events".by_day (
+    day text,
+    id uuid,
+    PRIMARY KEY (day, id)
+) WITH comment = 'a; b ''c'''
+    AND compaction = {'class': 'SizeTieredCompactionStrategy'};
+
+CREATE CUSTOM INDEX by_day_id ON "Events".by_day (id) USING 'org.apache.cassandra.index.sasi.SASIIndex';
+
+ALTER TABLE "Events".by_day DROP old USING TIMESTAMP 1559390400123000;
+
+`
+
+func TestParse(t *testing.T) {
+	statements, err := Parse(testScript)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var summaries []string
+	for _, statement := range statements {
+		summaries = append(summaries, statement.String())
+	}
+	expected := []string{
+		"KEYSPACE Events",
+		"TYPE Events.address",
+		"TABLE Events.by_day",
+		"INDEX Events.by_day_id",
+		"ALTER Events.by_day",
+	}
+	if diff := deep.Equal(summaries, expected); diff != nil {
+		t.Fatal(diff)
+	}
+	if !strings.HasSuffix(statements[2].CQL, "comment = 'a; b ''c'''\n    AND compaction = {'class': 'SizeTieredCompactionStrategy'}") {
+		t.Fatalf("unexpected statement: %q", statements[2].CQL)
+	}
+
+	if _, err := Parse("CREATE TABLE ks.t (a text PRIMARY KEY) WITH comment = 'oops;"); err != UnterminatedQuote {
+		t.Fatalf("expected=%v actual=%v", UnterminatedQuote, err)
+	}
+	if _, err := Parse("DROP TABLE ks.t;"); err == nil {
+		t.Fatal("expected error")
+	}
+	if _, err := Parse("CREATE TABLE t (a text PRIMARY KEY);"); err == nil {
+		t.Fatal("expected error")
+	}
+	if _, err := Parse("ALTER TABLE t DROP a;"); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestReplication(t *testing.T) {
+	statements, err := Parse(testScript)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replication, err := statements[0].Replication()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"class":   "org.apache.cassandra.locator.NetworkTopologyStrategy",
+		"us-east": "3",
+	}
+	if diff := deep.Equal(replication, expected); diff != nil {
+		t.Fatal(diff)
+	}
+
+	rewritten, err := statements[0].WithReplication(map[string]string{"class": "SimpleStrategy", "replication_factor": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedCQL := `CREATE KEYSPACE "Events" WITH replication = {'class': 'SimpleStrategy', 'replication_factor': '1'} AND durable_writes = true`
+	if diff := deep.Equal(rewritten.CQL, expectedCQL); diff != nil {
+		t.Fatal(diff)
+	}
+
+	if _, err := statements[1].Replication(); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestParseMap(t *testing.T) {
+	parsed, err := parseMap(` 'class' : 'SimpleStrategy','replication_factor': 3 `)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := deep.Equal(parsed, map[string]string{"class": "SimpleStrategy", "replication_factor": "3"}); diff != nil {
+		t.Fatal(diff)
+	}
+	if _, err := parseMap(`'class' 'SimpleStrategy'`); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package show
+
+import (
+	"github.com/retailnext/cassandrabackup/output"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const (
+	viewManifest = "manifest"
+	viewFiles    = "files"
+	viewTables   = "tables"
+)
+
+var (
+	Cmd = kingpin.Command("show", "")
+
+	ManifestCmd         = Cmd.Command("manifest", "Show the contents of a manifest")
+	manifestCmdCluster  = ManifestCmd.Flag("cluster", "Cluster name").Required().String()
+	manifestCmdHostname = ManifestCmd.Flag("hostname", "Hostname").Required().String()
+	manifestCmdTime     = ManifestCmd.Flag("time", "Time of the manifest (unix seconds), defaults to the newest").Int64()
+	manifestCmdView     = ManifestCmd.Flag("view", "What to show: manifest, files or tables.").Default(viewManifest).Enum(viewManifest, viewFiles, viewTables)
+	manifestCmdOutput   = output.Flag(ManifestCmd)
+
+	DiffCmd = kingpin.Command("diff", "")
+
+	DiffManifestsCmd              = DiffCmd.Command("manifests", "Compare the data files of two manifests")
+	diffManifestsCmdCluster       = DiffManifestsCmd.Flag("cluster", "Cluster name").Required().String()
+	diffManifestsCmdHostname      = DiffManifestsCmd.Flag("hostname", "Hostname").Required().String()
+	diffManifestsCmdTime          = DiffManifestsCmd.Flag("time", "Time of the base manifest (unix seconds)").Required().Int64()
+	diffManifestsCmdOtherCluster  = DiffManifestsCmd.Flag("other-cluster", "Cluster name of the other manifest, if different").String()
+	diffManifestsCmdOtherHostname = DiffManifestsCmd.Flag("other-hostname", "Hostname of the other manifest, if different").String()
+	diffManifestsCmdOtherTime     = DiffManifestsCmd.Flag("other-time", "Time of the other manifest (unix seconds), defaults to the newest").Int64()
+	diffManifestsCmdView          = DiffManifestsCmd.Flag("view", "What to show: files or tables.").Default(viewFiles).Enum(viewFiles, viewTables)
+	diffManifestsCmdOutput        = output.Flag(DiffManifestsCmd)
+)
@@ -0,0 +1,110 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package show
+
+import (
+	"context"
+	"os"
+
+	"github.com/retailnext/cassandrabackup/bucket"
+	"github.com/retailnext/cassandrabackup/manifests"
+	"github.com/retailnext/cassandrabackup/output"
+	"github.com/retailnext/cassandrabackup/unixtime"
+)
+
+func DiffManifests(ctx context.Context) error {
+	client := bucket.OpenShared()
+	identity := manifests.NodeIdentity{
+		Cluster:  *diffManifestsCmdCluster,
+		Hostname: *diffManifestsCmdHostname,
+	}
+	other := identity
+	if *diffManifestsCmdOtherCluster != "" {
+		other.Cluster = *diffManifestsCmdOtherCluster
+	}
+	if *diffManifestsCmdOtherHostname != "" {
+		other.Hostname = *diffManifestsCmdOtherHostname
+	}
+
+	before, err := getManifest(ctx, client, identity, unixtime.Seconds(*diffManifestsCmdTime))
+	if err != nil {
+		return err
+	}
+	after, err := getManifest(ctx, client, other, unixtime.Seconds(*diffManifestsCmdOtherTime))
+	if err != nil {
+		return err
+	}
+
+	changes := manifests.DiffDataFiles(before.DataFiles, after.DataFiles)
+	var records []output.Record
+	if *diffManifestsCmdView == viewTables {
+		records = changeSummaryRecords(changes)
+	} else {
+		records = changeRecords(changes)
+	}
+
+	w := output.NewWriter(*diffManifestsCmdOutput, os.Stdout)
+	for _, record := range records {
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+func changeRecords(changes []manifests.FileChange) []output.Record {
+	records := make([]output.Record, 0, len(changes))
+	for _, c := range changes {
+		var before, after interface{}
+		if c.Change != manifests.FileAdded {
+			before = c.Before.URLSafe()
+		}
+		if c.Change != manifests.FileRemoved {
+			after = c.After.URLSafe()
+		}
+		records = append(records, output.Record{
+			{Name: "table", Value: c.Table},
+			{Name: "change", Value: c.Change},
+			{Name: "path", Value: c.Path},
+			{Name: "before", Value: before},
+			{Name: "after", Value: after},
+		})
+	}
+	return records
+}
+
+func changeSummaryRecords(changes []manifests.FileChange) []output.Record {
+	var records []output.Record
+	counts := make(map[string]int)
+	flush := func(table string) {
+		records = append(records, output.Record{
+			{Name: "table", Value: table},
+			{Name: manifests.FileAdded, Value: counts[manifests.FileAdded]},
+			{Name: manifests.FileRemoved, Value: counts[manifests.FileRemoved]},
+			{Name: manifests.FileChanged, Value: counts[manifests.FileChanged]},
+		})
+		counts = make(map[string]int)
+	}
+	for i, c := range changes {
+		if i > 0 && c.Table != changes[i-1].Table {
+			flush(changes[i-1].Table)
+		}
+		counts[c.Change]++
+	}
+	if len(changes) > 0 {
+		flush(changes[len(changes)-1].Table)
+	}
+	return records
+}
@@ -0,0 +1,39 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package show
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/retailnext/cassandrabackup/manifests"
+	"github.com/retailnext/cassandrabackup/output"
+)
+
+func TestChangeSummaryRecords(t *testing.T) {
+	changes := []manifests.FileChange{
+		{Table: "ks.t1", Path: "ks/t1-0123/md-1-big-Data.db", Change: manifests.FileChanged},
+		{Table: "ks.t1", Path: "ks/t1-0123/md-2-big-Data.db", Change: manifests.FileRemoved},
+		{Table: "ks.t2", Path: "ks/t2-4567/md-2-big-Data.db", Change: manifests.FileAdded},
+	}
+
+	expectedSummary := []output.Record{
+		{{Name: "table", Value: "ks.t1"}, {Name: "added", Value: 0}, {Name: "removed", Value: 1}, {Name: "changed", Value: 1}},
+		{{Name: "table", Value: "ks.t2"}, {Name: "added", Value: 1}, {Name: "removed", Value: 0}, {Name: "changed", Value: 0}},
+	}
+	if diff := deep.Equal(changeSummaryRecords(changes), expectedSummary); diff != nil {
+		t.Fatal(diff)
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package show
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sort"
+
+	"github.com/retailnext/cassandrabackup/bucket"
+	"github.com/retailnext/cassandrabackup/digest"
+	"github.com/retailnext/cassandrabackup/manifests"
+	"github.com/retailnext/cassandrabackup/output"
+	"github.com/retailnext/cassandrabackup/unixtime"
+)
+
+var ManifestNotFound = errors.New("manifest not found")
+
+func Manifest(ctx context.Context) error {
+	identity := manifests.NodeIdentity{
+		Cluster:  *manifestCmdCluster,
+		Hostname: *manifestCmdHostname,
+	}
+	m, err := getManifest(ctx, bucket.OpenShared(), identity, unixtime.Seconds(*manifestCmdTime))
+	if err != nil {
+		return err
+	}
+
+	w := output.NewWriter(*manifestCmdOutput, os.Stdout)
+	var records []output.Record
+	switch *manifestCmdView {
+	case viewFiles:
+		records = fileRecords(m)
+	case viewTables:
+		records = tableRecords(m)
+	default:
+		records = []output.Record{manifestRecord(identity, m)}
+	}
+	for _, record := range records {
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// getManifest returns the manifest at t, or the newest one if t is zero.
+func getManifest(ctx context.Context, client *bucket.Client, identity manifests.NodeIdentity, t unixtime.Seconds) (manifests.Manifest, error) {
+	var keys manifests.ManifestKeys
+	var err error
+	if t > 0 {
+		// startAfter includes manifests at exactly that time, notAfter does not.
+		keys, err = client.ListManifests(ctx, identity, t, t+1)
+	} else {
+		keys, err = client.ListManifests(ctx, identity, 0, 0)
+	}
+	if err != nil {
+		return manifests.Manifest{}, err
+	}
+	if len(keys) == 0 {
+		return manifests.Manifest{}, ManifestNotFound
+	}
+	loaded, err := client.GetManifests(ctx, identity, keys[len(keys)-1:])
+	if err != nil {
+		return manifests.Manifest{}, err
+	}
+	return loaded[0], nil
+}
+
+func manifestRecord(identity manifests.NodeIdentity, m manifests.Manifest) output.Record {
+	return output.Record{
+		{Name: "cluster", Value: identity.Cluster},
+		{Name: "hostname", Value: identity.Hostname},
+		{Name: "time", Value: m.Time},
+		{Name: "type", Value: m.ManifestType.String()},
+		{Name: "host_id", Value: m.HostID},
+		{Name: "address", Value: m.Address},
+		{Name: "partitioner", Value: m.Partitioner},
+		{Name: "tokens", Value: output.List(m.Tokens)},
+		{Name: "data_center", Value: m.DataCenter},
+		{Name: "rack", Value: m.Rack},
+		{Name: "release_version", Value: m.ReleaseVersion},
+		{Name: "schema_version", Value: m.SchemaVersion},
+		{Name: "tool_version", Value: m.ToolVersion},
+		{Name: "schema", Value: m.Schema},
+		{Name: "excluded_tables", Value: output.List(m.ExcludedTables)},
+		{Name: "tables", Value: len(tableRecords(m))},
+		{Name: "files", Value: len(m.DataFiles)},
+	}
+}
+
+func fileRecords(m manifests.Manifest) []output.Record {
+	records := make([]output.Record, 0, len(m.DataFiles))
+	for _, name := range sortedNames(m.DataFiles) {
+		var length, mtime interface{}
+		if info, ok := m.DataFileInfo[name]; ok {
+			length = info.Length
+			mtime = info.ModTime().UTC()
+		}
+		records = append(records, output.Record{
+			{Name: "table", Value: manifests.TableName(name)},
+			{Name: "path", Value: name},
+			{Name: "digest", Value: m.DataFiles[name].URLSafe()},
+			{Name: "length", Value: length},
+			{Name: "mtime", Value: mtime},
+		})
+	}
+	return records
+}
+
+func tableRecords(m manifests.Manifest) []output.Record {
+	counts := make(map[string]int)
+	sizes := make(map[string]int64)
+	for name := range m.DataFiles {
+		table := manifests.TableName(name)
+		counts[table]++
+		sizes[table] += m.DataFileInfo[name].Length
+	}
+	tables := make([]string, 0, len(counts))
+	for table := range counts {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	records := make([]output.Record, 0, len(tables))
+	for _, table := range tables {
+		var size interface{}
+		if len(m.DataFileInfo) > 0 {
+			// Older manifests don't record file lengths.
+			size = sizes[table]
+		}
+		records = append(records, output.Record{
+			{Name: "table", Value: table},
+			{Name: "files", Value: counts[table]},
+			{Name: "bytes", Value: size},
+		})
+	}
+	return records
+}
+
+func sortedNames(files map[string]digest.ForRestore) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,62 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package show
+
+import (
+	"context"
+	"testing"
+
+	"github.com/retailnext/cassandrabackup/bucket"
+	"github.com/retailnext/cassandrabackup/manifests"
+	"github.com/retailnext/cassandrabackup/unixtime"
+)
+
+func TestGetManifest(t *testing.T) {
+	ctx := context.Background()
+	client := bucket.NewClient(bucket.NewMemoryBackend(), "")
+	identity := manifests.NodeIdentity{Cluster: "test-cluster", Hostname: "test-host"}
+
+	if _, err := getManifest(ctx, client, identity, 0); err != ManifestNotFound {
+		t.Fatalf("expected=%v actual=%v", ManifestNotFound, err)
+	}
+	for _, m := range []manifests.Manifest{
+		{Time: 1000, ManifestType: manifests.ManifestTypeSnapshot, HostID: "a"},
+		{Time: 1001, ManifestType: manifests.ManifestTypeIncremental, HostID: "b"},
+		{Time: 1002, ManifestType: manifests.ManifestTypeIncremental, HostID: "c"},
+	} {
+		if err := client.PutManifest(ctx, identity, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := map[int64]string{
+		0:    "c",
+		1000: "a",
+		1001: "b",
+		1002: "c",
+	}
+	for input, expected := range cases {
+		m, err := getManifest(ctx, client, identity, unixtime.Seconds(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.HostID != expected {
+			t.Fatalf("time=%d expected=%q actual=%q", input, expected, m.HostID)
+		}
+	}
+	if _, err := getManifest(ctx, client, identity, 999); err != ManifestNotFound {
+		t.Fatalf("expected=%v actual=%v", ManifestNotFound, err)
+	}
+}
@@ -27,26 +27,46 @@ type NodeInfo struct {
 	HostID         string
 	Partitioner    string
 	Rack           string
+	ReleaseVersion string
+	SchemaVersion  string
 	Tokens         []string
 }
 
 func GetNodeInfo(addr string) (NodeInfo, error) {
 	var result NodeInfo
 
+	session, err := NewSession(addr)
+	if err != nil {
+		return result, err
+	}
+	defer session.Close()
+
+	q := session.Query(`SELECT bootstrapped, cluster_name, data_center, host_id, partitioner, rack, release_version, schema_version, tokens FROM system.local`)
+	err = q.Scan(&result.BootstrapState, &result.ClusterName, &result.DataCenter, &result.HostID, &result.Partitioner, &result.Rack, &result.ReleaseVersion, &result.SchemaVersion, &result.Tokens)
+	sort.Strings(result.Tokens)
+
+	return result, err
+}
+
+// NewSession connects to a single node without discovering the rest of its cluster.
+func NewSession(addr string) (*gocql.Session, error) {
 	cluster := gocql.NewCluster(addr)
 	cluster.NumConns = 1
 	cluster.DisableInitialHostLookup = true
 	cluster.Consistency = gocql.LocalOne
+	return cluster.CreateSession()
+}
 
-	session, err := cluster.CreateSession()
+// GetTableID returns the ID of a table, as used in the names of its data directories.
+func GetTableID(addr, keyspace, table string) (gocql.UUID, error) {
+	var id gocql.UUID
+	session, err := NewSession(addr)
 	if err != nil {
-		return result, err
+		return id, err
 	}
 	defer session.Close()
 
-	q := session.Query(`SELECT bootstrapped, cluster_name, data_center, host_id, partitioner, rack, tokens FROM system.local`)
-	err = q.Scan(&result.BootstrapState, &result.ClusterName, &result.DataCenter, &result.HostID, &result.Partitioner, &result.Rack, &result.Tokens)
-	sort.Strings(result.Tokens)
-
-	return result, err
+	q := session.Query(`SELECT id FROM system_schema.tables WHERE keyspace_name = ? AND table_name = ?`, keyspace, table)
+	err = q.Scan(&id)
+	return id, err
 }
@@ -0,0 +1,520 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package systemlocal
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/retailnext/cassandrabackup/manifests"
+	"go.uber.org/zap"
+)
+
+// Schema is the user-defined part of a cluster's schema as read from system_schema.
+// Materialized views, functions and aggregates are not included.
+type Schema struct {
+	Keyspaces []Keyspace
+}
+
+type Keyspace struct {
+	Name          string
+	DurableWrites bool
+	Replication   map[string]string
+	Types         []UserType
+	Tables        []Table
+}
+
+type UserType struct {
+	Name       string
+	FieldNames []string
+	FieldTypes []string
+}
+
+type Table struct {
+	Name    string
+	ID      string
+	Flags   []string
+	Columns []Column
+	// Options holds the remaining system_schema.tables columns, which vary between Cassandra versions.
+	Options map[string]interface{}
+	Indexes []Index
+	// DroppedColumns must be recreated for sstables written before the drop to stay readable.
+	DroppedColumns []DroppedColumn
+}
+
+type Column struct {
+	Name            string
+	Kind            string
+	Position        int
+	Type            string
+	ClusteringOrder string
+}
+
+type DroppedColumn struct {
+	Name string
+	Type string
+	// Kind is "regular" or "static". It is empty when read from Cassandra versions before 4.0.
+	Kind        string
+	DroppedTime time.Time
+}
+
+type Index struct {
+	Name    string
+	Kind    string
+	Options map[string]string
+}
+
+// GetSchema reads the schema of every non-system keyspace.
+func GetSchema(addr string) (Schema, error) {
+	session, err := NewSession(addr)
+	if err != nil {
+		return Schema{}, err
+	}
+	defer session.Close()
+
+	keyspaces := make(map[string]*Keyspace)
+	var keyspaceNames []string
+	iter := session.Query(`SELECT keyspace_name, durable_writes, replication FROM system_schema.keyspaces`).Iter()
+	var ks Keyspace
+	for iter.Scan(&ks.Name, &ks.DurableWrites, &ks.Replication) {
+		if !manifests.IsSystemKeyspace(ks.Name) {
+			keyspace := ks
+			keyspaces[ks.Name] = &keyspace
+			keyspaceNames = append(keyspaceNames, ks.Name)
+		}
+		ks = Keyspace{}
+	}
+	if err := iter.Close(); err != nil {
+		return Schema{}, err
+	}
+
+	iter = session.Query(`SELECT keyspace_name, type_name, field_names, field_types FROM system_schema.types`).Iter()
+	var keyspaceName string
+	var userType UserType
+	for iter.Scan(&keyspaceName, &userType.Name, &userType.FieldNames, &userType.FieldTypes) {
+		if keyspace, ok := keyspaces[keyspaceName]; ok {
+			keyspace.Types = append(keyspace.Types, userType)
+		}
+		userType = UserType{}
+	}
+	if err := iter.Close(); err != nil {
+		return Schema{}, err
+	}
+
+	tables := make(map[[2]string]*Table)
+	var tableKeys [][2]string
+	iter = session.Query(`SELECT * FROM system_schema.tables`).Iter()
+	for {
+		row := make(map[string]interface{})
+		if !iter.MapScan(row) {
+			break
+		}
+		keyspaceName, _ := row["keyspace_name"].(string)
+		if _, ok := keyspaces[keyspaceName]; !ok {
+			continue
+		}
+		table := &Table{
+			Options: make(map[string]interface{}),
+		}
+		table.Name, _ = row["table_name"].(string)
+		if id, ok := row["id"].(gocql.UUID); ok {
+			table.ID = id.String()
+		}
+		table.Flags, _ = row["flags"].([]string)
+		for name, value := range row {
+			switch name {
+			case "keyspace_name", "table_name", "id", "flags", "extensions":
+			default:
+				table.Options[name] = value
+			}
+		}
+		key := [2]string{keyspaceName, table.Name}
+		tables[key] = table
+		tableKeys = append(tableKeys, key)
+	}
+	if err := iter.Close(); err != nil {
+		return Schema{}, err
+	}
+
+	iter = session.Query(`SELECT keyspace_name, table_name, column_name, kind, position, type, clustering_order FROM system_schema.columns`).Iter()
+	var tableName string
+	var column Column
+	for iter.Scan(&keyspaceName, &tableName, &column.Name, &column.Kind, &column.Position, &column.Type, &column.ClusteringOrder) {
+		if table, ok := tables[[2]string{keyspaceName, tableName}]; ok {
+			table.Columns = append(table.Columns, column)
+		}
+		column = Column{}
+	}
+	if err := iter.Close(); err != nil {
+		return Schema{}, err
+	}
+
+	iter = session.Query(`SELECT keyspace_name, table_name, index_name, kind, options FROM system_schema.indexes`).Iter()
+	var index Index
+	for iter.Scan(&keyspaceName, &tableName, &index.Name, &index.Kind, &index.Options) {
+		if table, ok := tables[[2]string{keyspaceName, tableName}]; ok {
+			table.Indexes = append(table.Indexes, index)
+		}
+		index = Index{}
+	}
+	if err := iter.Close(); err != nil {
+		return Schema{}, err
+	}
+
+	// Only Cassandra 4.0 and later have the kind column.
+	iter = session.Query(`SELECT * FROM system_schema.dropped_columns`).Iter()
+	for {
+		row := make(map[string]interface{})
+		if !iter.MapScan(row) {
+			break
+		}
+		keyspaceName, _ := row["keyspace_name"].(string)
+		tableName, _ := row["table_name"].(string)
+		table, ok := tables[[2]string{keyspaceName, tableName}]
+		if !ok {
+			continue
+		}
+		var dropped DroppedColumn
+		dropped.Name, _ = row["column_name"].(string)
+		dropped.Type, _ = row["type"].(string)
+		dropped.Kind, _ = row["kind"].(string)
+		dropped.DroppedTime, _ = row["dropped_time"].(time.Time)
+		table.DroppedColumns = append(table.DroppedColumns, dropped)
+	}
+	if err := iter.Close(); err != nil {
+		return Schema{}, err
+	}
+
+	for _, key := range tableKeys {
+		keyspace := keyspaces[key[0]]
+		keyspace.Tables = append(keyspace.Tables, *tables[key])
+	}
+	var result Schema
+	sort.Strings(keyspaceNames)
+	for _, name := range keyspaceNames {
+		result.Keyspaces = append(result.Keyspaces, *keyspaces[name])
+	}
+	return result, nil
+}
+
+// CQL renders the schema as a script of CREATE statements, each terminated by a semicolon
+// at the end of a line. Dropped columns are recreated and then dropped again with ALTER TABLE.
+func (s Schema) CQL() string {
+	var b strings.Builder
+	for _, keyspace := range s.Keyspaces {
+		keyspace.writeCQL(&b)
+	}
+	return b.String()
+}
+
+func (k Keyspace) writeCQL(b *strings.Builder) {
+	fmt.Fprintf(b, "CREATE KEYSPACE %s WITH replication = %s AND durable_writes = %t;\n\n",
+		QuoteIdentifier(k.Name), QuoteMap(k.Replication), k.DurableWrites)
+
+	for _, userType := range sortTypes(k.Types) {
+		fmt.Fprintf(b, "CREATE TYPE %s.%s (\n", QuoteIdentifier(k.Name), QuoteIdentifier(userType.Name))
+		for i, name := range userType.FieldNames {
+			separator := ","
+			if i == len(userType.FieldNames)-1 {
+				separator = ""
+			}
+			fmt.Fprintf(b, "    %s %s%s\n", QuoteIdentifier(name), userType.FieldTypes[i], separator)
+		}
+		b.WriteString(");\n\n")
+	}
+
+	tables := make([]Table, len(k.Tables))
+	copy(tables, k.Tables)
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].Name < tables[j].Name
+	})
+	for _, table := range tables {
+		table.writeCQL(b, k.Name)
+	}
+}
+
+func (t Table) writeCQL(b *strings.Builder, keyspace string) {
+	name := QuoteIdentifier(keyspace) + "." + QuoteIdentifier(t.Name)
+	compactStorage := len(t.Flags) > 0 && (t.hasFlag("dense") || !t.hasFlag("compound"))
+
+	columns := make([]Column, len(t.Columns))
+	copy(columns, t.Columns)
+	dropped := make([]DroppedColumn, len(t.DroppedColumns))
+	copy(dropped, t.DroppedColumns)
+	sort.Slice(dropped, func(i, j int) bool {
+		return dropped[i].Name < dropped[j].Name
+	})
+	existing := make(map[string]Column, len(columns))
+	for _, column := range columns {
+		existing[column.Name] = column
+	}
+	for _, column := range dropped {
+		if _, ok := existing[column.Name]; !ok {
+			kind := "regular"
+			if column.Kind == "static" {
+				kind = "static"
+			}
+			columns = append(columns, Column{Name: column.Name, Kind: kind, Position: -1, Type: column.Type})
+		}
+	}
+
+	var partitionKey, clustering, others []Column
+	for _, column := range columns {
+		switch column.Kind {
+		case "partition_key":
+			partitionKey = append(partitionKey, column)
+		case "clustering":
+			clustering = append(clustering, column)
+		default:
+			if compactStorage && column.Type == "empty" {
+				// Implicit value column of a dense table.
+				continue
+			}
+			others = append(others, column)
+		}
+	}
+	sort.SliceStable(partitionKey, func(i, j int) bool {
+		return partitionKey[i].Position < partitionKey[j].Position
+	})
+	sort.SliceStable(clustering, func(i, j int) bool {
+		return clustering[i].Position < clustering[j].Position
+	})
+	sort.SliceStable(others, func(i, j int) bool {
+		return others[i].Name < others[j].Name
+	})
+
+	fmt.Fprintf(b, "CREATE TABLE %s (\n", name)
+	for _, columns := range [][]Column{partitionKey, clustering, others} {
+		for _, column := range columns {
+			static := ""
+			if column.Kind == "static" {
+				static = " static"
+			}
+			fmt.Fprintf(b, "    %s %s%s,\n", QuoteIdentifier(column.Name), column.Type, static)
+		}
+	}
+	partitionKeyNames := columnNames(partitionKey)
+	if len(partitionKey) > 1 {
+		partitionKeyNames = "(" + partitionKeyNames + ")"
+	}
+	if len(clustering) > 0 {
+		fmt.Fprintf(b, "    PRIMARY KEY (%s, %s)\n", partitionKeyNames, columnNames(clustering))
+	} else {
+		fmt.Fprintf(b, "    PRIMARY KEY (%s)\n", partitionKeyNames)
+	}
+	b.WriteString(")")
+
+	var options []string
+	if compactStorage {
+		options = append(options, "COMPACT STORAGE")
+	}
+	if len(clustering) > 0 {
+		orders := make([]string, 0, len(clustering))
+		for _, column := range clustering {
+			order := "ASC"
+			if strings.EqualFold(column.ClusteringOrder, "desc") {
+				order = "DESC"
+			}
+			orders = append(orders, QuoteIdentifier(column.Name)+" "+order)
+		}
+		options = append(options, fmt.Sprintf("CLUSTERING ORDER BY (%s)", strings.Join(orders, ", ")))
+	}
+	if t.ID != "" {
+		// Restored sstables are found by the table-<id> directories they were backed up from.
+		options = append(options, "ID = "+t.ID)
+	}
+	optionNames := make([]string, 0, len(t.Options))
+	for optionName := range t.Options {
+		optionNames = append(optionNames, optionName)
+	}
+	sort.Strings(optionNames)
+	for _, optionName := range optionNames {
+		optionValue := t.Options[optionName]
+		if value, ok := formatOption(optionValue); ok {
+			options = append(options, optionName+" = "+value)
+		} else if optionValue != nil {
+			zap.S().Warnw("schema_option_skipped", "table", name, "option", optionName, "type", fmt.Sprintf("%T", optionValue))
+		}
+	}
+	for i, option := range options {
+		if i == 0 {
+			b.WriteString(" WITH ")
+		} else {
+			b.WriteString("\n    AND ")
+		}
+		b.WriteString(option)
+	}
+	b.WriteString(";\n\n")
+
+	for _, column := range dropped {
+		// USING TIMESTAMP takes microseconds, which is how Cassandra keeps track of the drop.
+		fmt.Fprintf(b, "ALTER TABLE %s DROP %s USING TIMESTAMP %d;\n\n",
+			name, QuoteIdentifier(column.Name), column.DroppedTime.UnixNano()/int64(time.Microsecond))
+		if readded, ok := existing[column.Name]; ok {
+			static := ""
+			if readded.Kind == "static" {
+				static = " static"
+			}
+			fmt.Fprintf(b, "ALTER TABLE %s ADD %s %s%s;\n\n", name, QuoteIdentifier(readded.Name), readded.Type, static)
+		}
+	}
+
+	indexes := make([]Index, len(t.Indexes))
+	copy(indexes, t.Indexes)
+	sort.Slice(indexes, func(i, j int) bool {
+		return indexes[i].Name < indexes[j].Name
+	})
+	for _, index := range indexes {
+		target := index.Options["target"]
+		if index.Kind != "CUSTOM" {
+			fmt.Fprintf(b, "CREATE INDEX %s ON %s (%s);\n\n", QuoteIdentifier(index.Name), name, target)
+			continue
+		}
+		fmt.Fprintf(b, "CREATE CUSTOM INDEX %s ON %s (%s) USING %s", QuoteIdentifier(index.Name), name, target, quoteString(index.Options["class_name"]))
+		indexOptions := make(map[string]string)
+		for k, v := range index.Options {
+			if k != "target" && k != "class_name" {
+				indexOptions[k] = v
+			}
+		}
+		if len(indexOptions) > 0 {
+			fmt.Fprintf(b, " WITH OPTIONS = %s", QuoteMap(indexOptions))
+		}
+		b.WriteString(";\n\n")
+	}
+}
+
+func (t Table) hasFlag(flag string) bool {
+	for _, f := range t.Flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+func columnNames(columns []Column) string {
+	names := make([]string, 0, len(columns))
+	for _, column := range columns {
+		names = append(names, QuoteIdentifier(column.Name))
+	}
+	return strings.Join(names, ", ")
+}
+
+// sortTypes orders user types so that types used by other types' fields come first.
+func sortTypes(types []UserType) []UserType {
+	remaining := make([]UserType, len(types))
+	copy(remaining, types)
+	sort.Slice(remaining, func(i, j int) bool {
+		return remaining[i].Name < remaining[j].Name
+	})
+
+	var result []UserType
+	for len(remaining) > 0 {
+		var deferred []UserType
+		for _, candidate := range remaining {
+			if usesAny(candidate, remaining) {
+				deferred = append(deferred, candidate)
+			} else {
+				result = append(result, candidate)
+			}
+		}
+		if len(deferred) == len(remaining) {
+			// A cycle isn't possible in a valid schema, but don't loop forever.
+			return append(result, deferred...)
+		}
+		remaining = deferred
+	}
+	return result
+}
+
+func usesAny(userType UserType, others []UserType) bool {
+	for _, other := range others {
+		if other.Name == userType.Name {
+			continue
+		}
+		expr := regexp.MustCompile(`\b` + regexp.QuoteMeta(other.Name) + `\b`)
+		for _, fieldType := range userType.FieldTypes {
+			if expr.MatchString(fieldType) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func formatOption(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return quoteString(v), true
+	case map[string]string:
+		return QuoteMap(v), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case int:
+		return strconv.Itoa(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		return "", false
+	}
+}
+
+var unquotedIdentifier = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+var reservedWords = map[string]struct{}{
+	"add": {}, "allow": {}, "alter": {}, "and": {}, "apply": {}, "asc": {}, "authorize": {}, "batch": {},
+	"begin": {}, "by": {}, "columnfamily": {}, "create": {}, "delete": {}, "desc": {}, "describe": {},
+	"drop": {}, "entries": {}, "execute": {}, "from": {}, "full": {}, "grant": {}, "if": {}, "in": {},
+	"index": {}, "infinity": {}, "insert": {}, "into": {}, "is": {}, "keyspace": {}, "limit": {},
+	"materialized": {}, "mbean": {}, "mbeans": {}, "modify": {}, "nan": {}, "norecursive": {}, "not": {},
+	"null": {}, "of": {}, "on": {}, "or": {}, "order": {}, "primary": {}, "rename": {}, "replace": {},
+	"revoke": {}, "schema": {}, "select": {}, "set": {}, "table": {}, "to": {}, "token": {},
+	"truncate": {}, "unlogged": {}, "unset": {}, "update": {}, "use": {}, "using": {}, "view": {},
+	"where": {}, "with": {},
+}
+
+// QuoteIdentifier double-quotes a keyspace, table or column name if CQL requires it.
+func QuoteIdentifier(name string) string {
+	if _, reserved := reservedWords[name]; !reserved && unquotedIdentifier.MatchString(name) {
+		return name
+	}
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+func quoteString(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// QuoteMap renders a CQL map literal of strings, such as a keyspace's replication options.
+func QuoteMap(m map[string]string) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	entries := make([]string, 0, len(keys))
+	for _, k := range keys {
+		entries = append(entries, quoteString(k)+": "+quoteString(m[k]))
+	}
+	return "{" + strings.Join(entries, ", ") + "}"
+}
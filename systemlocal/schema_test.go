@@ -0,0 +1,152 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package systemlocal
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+)
+
+func TestSchemaCQL(t *testing.T) {
+	schema := Schema{
+		Keyspaces: []Keyspace{
+			{
+				Name:          "ks",
+				DurableWrites: true,
+				Replication: map[string]string{
+					"class": "org.apache.cassandra.locator.NetworkTopologyStrategy",
+					"dc1":   "3",
+				},
+				Types: []UserType{
+					{Name: "b_outer", FieldNames: []string{"inner"}, FieldTypes: []string{"frozen<a_inner>"}},
+					{Name: "a_inner", FieldNames: []string{"x", "y"}, FieldTypes: []string{"int", "text"}},
+				},
+				Tables: []Table{
+					{
+						Name:  "events",
+						ID:    "5bc52802-de25-35ed-aeab-188eecebb090",
+						Flags: []string{"compound"},
+						Columns: []Column{
+							{Name: "value", Kind: "regular", Position: -1, Type: "text"},
+							{Name: "time", Kind: "clustering", Position: 0, Type: "timestamp", ClusteringOrder: "desc"},
+							{Name: "id", Kind: "partition_key", Position: 1, Type: "uuid"},
+							{Name: "Shard", Kind: "partition_key", Position: 0, Type: "int"},
+							{Name: "owner", Kind: "static", Position: -1, Type: "text"},
+						},
+						Options: map[string]interface{}{
+							"comment":            "it's",
+							"gc_grace_seconds":   864000,
+							"compaction":         map[string]string{"class": "TimeWindowCompactionStrategy"},
+							"crc_check_chance":   1.0,
+							"extensions_or_blob": []byte{1},
+						},
+						Indexes: []Index{
+							{Name: "events_value", Kind: "COMPOSITES", Options: map[string]string{"target": "value"}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	expected := `CREATE KEYSPACE ks WITH replication = {'class': 'org.apache.cassandra.locator.NetworkTopologyStrategy', 'dc1': '3'} AND durable_writes = true;
+
+CREATE TYPE ks.a_inner (
+    x int,
+    y text
+);
+
+CREATE TYPE ks.b_outer (
+    inner frozen<a_inner>
+);
+
+CREATE TABLE ks.events (
+    "Shard" int,
+    id uuid,
+    time timestamp,
+    owner text static,
+    value text,
+    PRIMARY KEY (("Shard", id), time)
+) WITH CLUSTERING ORDER BY (time DESC)
+    AND ID = 5bc52802-de25-35ed-aeab-188eecebb090
+    AND comment = 'it''s'
+    AND compaction = {'class': 'TimeWindowCompactionStrategy'}
+    AND crc_check_chance = 1
+    AND gc_grace_seconds = 864000;
+
+CREATE INDEX events_value ON ks.events (value);
+
+`
+	if diff := deep.Equal(schema.CQL(), expected); diff != nil {
+		t.Fatal(diff)
+	}
+}
+
+func TestSchemaCQLDroppedColumns(t *testing.T) {
+	dropped := time.Date(2019, 6, 1, 12, 0, 0, 123000000, time.UTC)
+	table := Table{
+		Name:  "profiles",
+		Flags: []string{"compound"},
+		Columns: []Column{
+			{Name: "id", Kind: "partition_key", Position: 0, Type: "uuid"},
+			{Name: "email", Kind: "regular", Position: -1, Type: "text"},
+		},
+		DroppedColumns: []DroppedColumn{
+			{Name: "Nickname", Type: "text", Kind: "regular", DroppedTime: dropped},
+			{Name: "email", Type: "blob", DroppedTime: dropped},
+			{Name: "region", Type: "int", Kind: "static", DroppedTime: dropped},
+		},
+	}
+
+	expected := `CREATE TABLE ks.profiles (
+    id uuid,
+    "Nickname" text,
+    email text,
+    region int static,
+    PRIMARY KEY (id)
+);
+
+ALTER TABLE ks.profiles DROP "Nickname" USING TIMESTAMP 1559390400123000;
+
+ALTER TABLE ks.profiles DROP email USING TIMESTAMP 1559390400123000;
+
+ALTER TABLE ks.profiles ADD email text;
+
+ALTER TABLE ks.profiles DROP region USING TIMESTAMP 1559390400123000;
+
+`
+	var b strings.Builder
+	table.writeCQL(&b, "ks")
+	if diff := deep.Equal(b.String(), expected); diff != nil {
+		t.Fatal(diff)
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	for name, expected := range map[string]string{
+		"simple_1": "simple_1",
+		"Mixed":    `"Mixed"`,
+		"select":   `"select"`,
+		`a"b`:      `"a""b"`,
+		"1abc":     `"1abc"`,
+	} {
+		if actual := QuoteIdentifier(name); actual != expected {
+			t.Errorf("QuoteIdentifier(%q)=%q expected %q", name, actual, expected)
+		}
+	}
+}
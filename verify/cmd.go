@@ -0,0 +1,34 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import "gopkg.in/alecthomas/kingpin.v2"
+
+var (
+	Cmd = kingpin.Command("verify", "Check that every blob referenced by backup manifests is present in the bucket, optionally re-hashing their contents.")
+
+	cmdClusters    = Cmd.Flag("cluster", "Only verify manifests in these clusters.").Strings()
+	cmdHostnames   = Cmd.Flag("hostname", "Only verify manifests for these hostnames.").Strings()
+	cmdNotBefore   = Cmd.Flag("not-before", "Ignore manifests before this time (unix seconds)").Int64()
+	cmdNotAfter    = Cmd.Flag("not-after", "Ignore manifests after this time (unix seconds)").Int64()
+	cmdLockWarning = Cmd.Flag("lock-warning", "Report blobs whose object lock expires within this long.").Default("168h").Duration()
+	cmdReportFile  = Cmd.Flag("report-file", "Write the JSON report to this file instead of stdout.").String()
+	cmdConcurrency = Cmd.Flag("concurrency", "Number of blobs to check at once.").Default("16").Int()
+	cmdMetricsFile = Cmd.Flag("metrics-textfile", "Write metrics to this file for node_exporter's textfile collector, e.g. /var/lib/node_exporter/cassandrabackup_verify.prom.").String()
+
+	cmdScrubFraction   = Cmd.Flag("scrub-fraction", "Also download and re-hash this fraction (0 to 1) of the referenced blobs.").Default("0").Float64()
+	cmdScrubRateLimit  = Cmd.Flag("scrub-rate-limit", "Limit scrub downloads to this many bytes per second in total (0 for unlimited).").Default("0").Bytes()
+	cmdScrubAgainAfter = Cmd.Flag("scrub-again-after", "Don't scrub blobs already scrubbed within this long, so interrupted scrubs resume where they left off.").Default("168h").Duration()
+)
@@ -0,0 +1,102 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+	"github.com/retailnext/cassandrabackup/writefile"
+)
+
+var (
+	checkedManifests = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "cassandrabackup",
+		Subsystem: "verify",
+		Name:      "manifests_total",
+		Help:      "Number of manifests verified.",
+	})
+	checkedBlobs = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "cassandrabackup",
+		Subsystem: "verify",
+		Name:      "blobs_total",
+		Help:      "Number of distinct blobs checked.",
+	})
+	scrubbedBlobs = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "cassandrabackup",
+		Subsystem: "verify",
+		Name:      "scrubbed_blobs_total",
+		Help:      "Number of blobs downloaded and found to match their digest.",
+	})
+	scrubbedBytes = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "cassandrabackup",
+		Subsystem: "verify",
+		Name:      "scrubbed_bytes_total",
+		Help:      "Total bytes downloaded while scrubbing blobs.",
+	})
+	problemCounters = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "cassandrabackup",
+		Subsystem: "verify",
+		Name:      "problems_total",
+		Help:      "Number of problem blobs found.",
+	}, []string{"problem"})
+	unrestorableManifests = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "cassandrabackup",
+		Subsystem: "verify",
+		Name:      "unrestorable_manifests",
+		Help:      "Number of manifests found by the last verification to reference missing or damaged blobs.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(checkedManifests)
+	prometheus.MustRegister(checkedBlobs)
+	prometheus.MustRegister(scrubbedBlobs)
+	prometheus.MustRegister(scrubbedBytes)
+	prometheus.MustRegister(problemCounters)
+	prometheus.MustRegister(unrestorableManifests)
+}
+
+// writeMetricsTextfile writes the tool's metrics to name in the text format read by node_exporter's
+// textfile collector, since a one-shot verification is gone before it could be scraped.
+func writeMetricsTextfile(name string) error {
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		return err
+	}
+	name, err = filepath.Abs(name)
+	if err != nil {
+		return err
+	}
+	target := writefile.Config{
+		Directory: filepath.Dir(name),
+		FileMode:  0644,
+	}
+	return target.WriteFile(filepath.Base(name), func(file *os.File) error {
+		for _, family := range families {
+			// The go_ and process_ metrics would clash with node_exporter's own.
+			if !strings.HasPrefix(family.GetName(), "cassandrabackup_") {
+				continue
+			}
+			if _, err := expfmt.MetricFamilyToText(file, family); err != nil {
+				return err
+			}
+		}
+		return file.Sync()
+	})
+}
@@ -0,0 +1,195 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/retailnext/cassandrabackup/bucket"
+	"github.com/retailnext/cassandrabackup/cache"
+	"github.com/retailnext/cassandrabackup/digest"
+	"github.com/retailnext/cassandrabackup/envelope"
+	"github.com/retailnext/cassandrabackup/unixtime"
+	"go.uber.org/zap"
+)
+
+// shouldScrub samples blobs that haven't been scrubbed recently.
+func (v *verifier) shouldScrub(digests digest.ForRestore) bool {
+	if v.scrubFraction <= 0 {
+		return false
+	}
+	if v.scrubFraction < 1 && rand.Float64() >= v.scrubFraction {
+		return false
+	}
+	return !v.scrubProgress.scrubbedSince(digests, time.Now().Add(-v.scrubAgainAfter))
+}
+
+// scrubBlob downloads the blob and checks its digest without writing it to disk.
+// Only failures to read the blob are returned as errors; anything wrong with its contents is a problem.
+func (v *verifier) scrubBlob(ctx context.Context, digests digest.ForRestore) (Problem, error) {
+	body, err := v.client.OpenBlob(ctx, digests)
+	if err == bucket.NotFound {
+		return ProblemMissing, nil
+	} else if err != nil {
+		return "", err
+	}
+	defer func() {
+		if closeErr := body.Close(); closeErr != nil {
+			zap.S().Warnw("scrub_close_error", "digest", digests, "err", closeErr)
+		}
+	}()
+
+	counted := &countingReader{reader: body}
+	reader := io.Reader(counted)
+	if v.scrubLimiter != nil {
+		reader = &throttledReader{ctx: ctx, reader: reader, limiter: v.scrubLimiter}
+	}
+	err = digests.VerifyStream(ctx, reader)
+	scrubbedBytes.Add(float64(counted.n))
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", ctxErr
+		}
+		if _, ok := err.(digest.MismatchError); ok || envelope.IsInvalid(err) {
+			return ProblemCorrupt, nil
+		}
+		return "", err
+	}
+	scrubbedBlobs.Inc()
+	v.scrubProgress.markScrubbed(digests)
+	return "", nil
+}
+
+// scrubProgress remembers which blobs were scrubbed when, so that interrupted scrubs can resume.
+// It is safe to use a nil scrubProgress.
+type scrubProgress struct {
+	cache *cache.Cache
+}
+
+func openScrubProgress() *scrubProgress {
+	cache.OpenShared()
+	return &scrubProgress{
+		cache: cache.Shared.Cache("verify_scrubbed"),
+	}
+}
+
+func (p *scrubProgress) scrubbedSince(digests digest.ForRestore, since time.Time) bool {
+	if p == nil {
+		return false
+	}
+	key, err := digests.MarshalBinary()
+	if err != nil {
+		panic(err)
+	}
+	var scrubbedAt unixtime.Seconds
+	err = p.cache.Get(key, func(value []byte) error {
+		return scrubbedAt.UnmarshalBinary(value)
+	})
+	if err != nil {
+		if err != cache.NotFound {
+			zap.S().Warnw("scrub_progress_get_error", "digest", digests, "err", err)
+		}
+		return false
+	}
+	return int64(scrubbedAt) >= since.Unix()
+}
+
+func (p *scrubProgress) markScrubbed(digests digest.ForRestore) {
+	if p == nil {
+		return
+	}
+	key, err := digests.MarshalBinary()
+	if err != nil {
+		panic(err)
+	}
+	value, err := unixtime.Now().MarshalBinary()
+	if err != nil {
+		panic(err)
+	}
+	if err := p.cache.Put(key, value); err != nil {
+		zap.S().Warnw("scrub_progress_put_error", "digest", digests, "err", err)
+	}
+}
+
+type countingReader struct {
+	reader io.Reader
+	n      int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// rateLimiter spaces out reads shared between goroutines to average bytesPerSecond.
+type rateLimiter struct {
+	bytesPerSecond int64
+
+	lock sync.Mutex
+	next time.Time
+}
+
+func newRateLimiter(bytesPerSecond int64) *rateLimiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		bytesPerSecond: bytesPerSecond,
+	}
+}
+
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	l.lock.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(n) * time.Second / time.Duration(l.bytesPerSecond))
+	l.lock.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+type throttledReader struct {
+	ctx     context.Context
+	reader  io.Reader
+	limiter *rateLimiter
+}
+
+func (r *throttledReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		if waitErr := r.limiter.wait(r.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,357 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/retailnext/cassandrabackup/bucket"
+	"github.com/retailnext/cassandrabackup/digest"
+	"github.com/retailnext/cassandrabackup/manifests"
+	"github.com/retailnext/cassandrabackup/unixtime"
+	"go.uber.org/zap"
+)
+
+var Unrestorable = errors.New("some manifests reference missing or damaged blobs")
+
+type Problem string
+
+const (
+	ProblemMissing      Problem = "missing"
+	ProblemDeleteMarker Problem = "delete_marker"
+	ProblemWrongLength  Problem = "wrong_length"
+	ProblemCorrupt      Problem = "corrupt"
+	// ProblemLockExpiring is only a warning; the blob is still restorable.
+	ProblemLockExpiring Problem = "lock_expiring"
+)
+
+func (p Problem) Fatal() bool {
+	return p != ProblemLockExpiring
+}
+
+type Report struct {
+	Manifests             int             `json:"manifests"`
+	Blobs                 int             `json:"blobs"`
+	Scrubbed              int             `json:"scrubbed"`
+	UnrestorableManifests int             `json:"unrestorable_manifests"`
+	Problems              []ProblemRecord `json:"problems"`
+}
+
+type ProblemRecord struct {
+	Cluster       string           `json:"cluster"`
+	Hostname      string           `json:"hostname"`
+	ManifestTime  unixtime.Seconds `json:"manifest_time"`
+	ManifestType  string           `json:"manifest_type"`
+	Path          string           `json:"path"`
+	Digest        string           `json:"digest"`
+	Problem       Problem          `json:"problem"`
+	ContentLength int64            `json:"content_length,omitempty"`
+	RetainUntil   *time.Time       `json:"retain_until,omitempty"`
+}
+
+func Main(ctx context.Context) error {
+	v := verifier{
+		client:      bucket.OpenShared(),
+		clusters:    *cmdClusters,
+		hostnames:   *cmdHostnames,
+		notBefore:   unixtime.Seconds(*cmdNotBefore),
+		notAfter:    unixtime.Seconds(*cmdNotAfter),
+		lockWarning: *cmdLockWarning,
+		concurrency: *cmdConcurrency,
+
+		scrubFraction:   *cmdScrubFraction,
+		scrubLimiter:    newRateLimiter(int64(*cmdScrubRateLimit)),
+		scrubAgainAfter: *cmdScrubAgainAfter,
+	}
+	if v.scrubFraction > 0 {
+		v.scrubProgress = openScrubProgress()
+	}
+	report, err := v.run(ctx)
+	if err != nil {
+		return err
+	}
+	if *cmdMetricsFile != "" {
+		if err := writeMetricsTextfile(*cmdMetricsFile); err != nil {
+			return err
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if *cmdReportFile != "" {
+		f, err := os.Create(*cmdReportFile)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := f.Close(); closeErr != nil {
+				zap.S().Errorw("verify_report_close_error", "err", closeErr)
+			}
+		}()
+		w = f
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+
+	if report.UnrestorableManifests > 0 {
+		return Unrestorable
+	}
+	return nil
+}
+
+type verifier struct {
+	client      *bucket.Client
+	clusters    []string
+	hostnames   []string
+	notBefore   unixtime.Seconds
+	notAfter    unixtime.Seconds
+	lockWarning time.Duration
+	concurrency int
+
+	scrubFraction   float64
+	scrubLimiter    *rateLimiter
+	scrubAgainAfter time.Duration
+	scrubProgress   *scrubProgress
+}
+
+// reference is one use of a blob by a manifest.
+type reference struct {
+	identity manifests.NodeIdentity
+	key      manifests.ManifestKey
+	path     string
+	// length is -1 for manifests that didn't record it.
+	length int64
+}
+
+type blobResult struct {
+	problem  Problem
+	info     bucket.BlobInfo
+	scrubbed bool
+}
+
+func (v *verifier) run(ctx context.Context) (Report, error) {
+	lgr := zap.S()
+	var report Report
+
+	identities, err := v.identities(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	references := make(map[digest.ForRestore][]reference)
+	for _, identity := range identities {
+		keys, err := v.client.ListManifests(ctx, identity, v.notBefore, v.notAfter)
+		if err != nil {
+			return report, err
+		}
+		loaded, err := v.client.GetManifests(ctx, identity, keys)
+		if err != nil {
+			return report, err
+		}
+		for _, m := range loaded {
+			for path, digests := range m.DataFiles {
+				length := int64(-1)
+				if info, ok := m.DataFileInfo[path]; ok {
+					length = info.Length
+				}
+				references[digests] = append(references[digests], reference{
+					identity: identity,
+					key:      m.Key(),
+					path:     path,
+					length:   length,
+				})
+			}
+		}
+		report.Manifests += len(loaded)
+		checkedManifests.Add(float64(len(loaded)))
+		lgr.Infow("verify_loaded_manifests", "identity", identity, "count", len(loaded))
+	}
+
+	results, scrubbed, err := v.checkBlobs(ctx, references)
+	if err != nil {
+		return report, err
+	}
+	report.Blobs = len(references)
+	report.Scrubbed = scrubbed
+
+	unrestorable := make(map[reference]struct{})
+	for digests, result := range results {
+		problemCounters.WithLabelValues(string(result.problem)).Inc()
+		for _, ref := range references[digests] {
+			record := ProblemRecord{
+				Cluster:       ref.identity.Cluster,
+				Hostname:      ref.identity.Hostname,
+				ManifestTime:  ref.key.Time,
+				ManifestType:  ref.key.ManifestType.String(),
+				Path:          ref.path,
+				Digest:        digests.URLSafe(),
+				Problem:       result.problem,
+				ContentLength: result.info.ContentLength,
+			}
+			if !result.info.RetainUntil.IsZero() {
+				retainUntil := result.info.RetainUntil
+				record.RetainUntil = &retainUntil
+			}
+			report.Problems = append(report.Problems, record)
+			if result.problem.Fatal() {
+				unrestorable[reference{identity: ref.identity, key: ref.key}] = struct{}{}
+			}
+		}
+	}
+	sort.Slice(report.Problems, func(i, j int) bool {
+		a, b := report.Problems[i], report.Problems[j]
+		if a.Cluster != b.Cluster {
+			return a.Cluster < b.Cluster
+		}
+		if a.Hostname != b.Hostname {
+			return a.Hostname < b.Hostname
+		}
+		if a.ManifestTime != b.ManifestTime {
+			return a.ManifestTime < b.ManifestTime
+		}
+		return a.Path < b.Path
+	})
+	report.UnrestorableManifests = len(unrestorable)
+	unrestorableManifests.Set(float64(len(unrestorable)))
+
+	lgr.Infow("verify_complete", "manifests", report.Manifests, "blobs", report.Blobs, "scrubbed", report.Scrubbed, "problems", len(report.Problems), "unrestorable_manifests", report.UnrestorableManifests)
+	return report, nil
+}
+
+func (v *verifier) identities(ctx context.Context) ([]manifests.NodeIdentity, error) {
+	clusters := v.clusters
+	if len(clusters) == 0 {
+		var err error
+		clusters, err = v.client.ListClusters(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	hostnames := make(map[string]struct{}, len(v.hostnames))
+	for _, hostname := range v.hostnames {
+		hostnames[hostname] = struct{}{}
+	}
+
+	var result []manifests.NodeIdentity
+	for _, cluster := range clusters {
+		identities, err := v.client.ListHostNames(ctx, cluster)
+		if err != nil {
+			return nil, err
+		}
+		for _, identity := range identities {
+			if _, ok := hostnames[identity.Hostname]; ok || len(hostnames) == 0 {
+				result = append(result, identity)
+			}
+		}
+	}
+	return result, nil
+}
+
+// checkBlobs returns the blobs that have problems, and how many blobs were scrubbed.
+func (v *verifier) checkBlobs(ctx context.Context, references map[digest.ForRestore][]reference) (map[digest.ForRestore]blobResult, int, error) {
+	concurrency := v.concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	limiter := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	var firstErr error
+	var scrubbed int
+	results := make(map[digest.ForRestore]blobResult)
+
+	doneCh := ctx.Done()
+	for digests, refs := range references {
+		expectedLength := int64(-1)
+		for _, ref := range refs {
+			if ref.length >= 0 {
+				expectedLength = ref.length
+				break
+			}
+		}
+		select {
+		case <-doneCh:
+		case limiter <- struct{}{}:
+			wg.Add(1)
+			go func(digests digest.ForRestore, expectedLength int64) {
+				defer func() {
+					<-limiter
+					wg.Done()
+				}()
+				result, err := v.checkBlob(ctx, digests, expectedLength)
+				lock.Lock()
+				defer lock.Unlock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+					}
+					return
+				}
+				if result.scrubbed {
+					scrubbed++
+				}
+				if result.problem != "" {
+					results[digests] = result
+				}
+			}(digests, expectedLength)
+		}
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	return results, scrubbed, firstErr
+}
+
+// checkBlob checks the blob against its plaintext expectedLength, unless that is negative because it is unknown.
+func (v *verifier) checkBlob(ctx context.Context, digests digest.ForRestore, expectedLength int64) (blobResult, error) {
+	checkedBlobs.Inc()
+	info, err := v.client.StatBlob(ctx, digests)
+	if err == bucket.NotFound {
+		return blobResult{problem: ProblemMissing}, nil
+	} else if err != nil {
+		return blobResult{}, err
+	}
+
+	result := blobResult{info: info}
+	switch {
+	case info.DeleteMarker:
+		result.problem = ProblemDeleteMarker
+	case expectedLength >= 0 && !v.client.PlausibleStoredLength(expectedLength, info.ContentLength):
+		result.problem = ProblemWrongLength
+	case !info.RetainUntil.IsZero() && info.RetainUntil.Before(time.Now().Add(v.lockWarning)):
+		result.problem = ProblemLockExpiring
+	}
+	if (result.problem == "" || !result.problem.Fatal()) && v.shouldScrub(digests) {
+		problem, err := v.scrubBlob(ctx, digests)
+		if err != nil {
+			return blobResult{}, err
+		}
+		if problem != "" {
+			result.problem = problem
+		}
+		result.scrubbed = true
+	}
+	return result, nil
+}
@@ -0,0 +1,352 @@
+// Copyright 2019 RetailNext, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/retailnext/cassandrabackup/bucket"
+	"github.com/retailnext/cassandrabackup/digest"
+	"github.com/retailnext/cassandrabackup/envelope"
+	"github.com/retailnext/cassandrabackup/manifests"
+	"github.com/retailnext/cassandrabackup/paranoid"
+)
+
+func putTestBlob(t *testing.T, client *bucket.Client, contents string) digest.ForRestore {
+	ctx := context.Background()
+	f, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	}()
+	if _, err := f.WriteString(contents); err != nil {
+		t.Fatal(err)
+	}
+	parFile, err := paranoid.NewFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	digests, err := digest.GetUncached(ctx, parFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.PutBlob(ctx, parFile, digests); err != nil {
+		t.Fatal(err)
+	}
+	return digests.ForRestore()
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	client := bucket.NewClient(bucket.NewMemoryBackend(), "")
+	identity := manifests.NodeIdentity{Cluster: "test-cluster", Hostname: "test-host"}
+
+	present := putTestBlob(t, client, "present")
+	missing := putTestBlob(t, client, "missing")
+	if err := client.DeleteBlob(ctx, missing); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, m := range []manifests.Manifest{
+		{
+			Time:         1000,
+			ManifestType: manifests.ManifestTypeSnapshot,
+			DataFiles: map[string]digest.ForRestore{
+				"ks/t-1/md-1-big-Data.db": present,
+			},
+		},
+		{
+			Time:         2000,
+			ManifestType: manifests.ManifestTypeIncremental,
+			DataFiles: map[string]digest.ForRestore{
+				"ks/t-1/md-1-big-Data.db": present,
+				"ks/t-1/md-2-big-Data.db": missing,
+			},
+		},
+	} {
+		if err := client.PutManifest(ctx, identity, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	v := verifier{client: client}
+	report, err := v.run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Manifests != 2 || report.Blobs != 2 || report.UnrestorableManifests != 1 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if len(report.Problems) != 1 {
+		t.Fatalf("expected one problem, got %+v", report.Problems)
+	}
+	problem := report.Problems[0]
+	if problem.Problem != ProblemMissing || problem.ManifestTime != 2000 || problem.Path != "ks/t-1/md-2-big-Data.db" {
+		t.Fatalf("unexpected problem %+v", problem)
+	}
+
+	v.notAfter = 1500
+	report, err = v.run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Manifests != 1 || report.UnrestorableManifests != 0 || len(report.Problems) != 0 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+}
+
+func TestScrub(t *testing.T) {
+	ctx := context.Background()
+	backend := bucket.NewMemoryBackend()
+	client := bucket.NewClient(backend, "")
+	identity := manifests.NodeIdentity{Cluster: "test-cluster", Hostname: "test-host"}
+
+	good := putTestBlob(t, client, "good")
+	bad := putTestBlob(t, client, "bad")
+	if err := backend.PutDocument(ctx, blobKey(bad), []byte("bat")); err != nil {
+		t.Fatal(err)
+	}
+
+	m := manifests.Manifest{
+		Time:         1000,
+		ManifestType: manifests.ManifestTypeSnapshot,
+		DataFiles: map[string]digest.ForRestore{
+			"ks/t-1/md-1-big-Data.db": good,
+			"ks/t-1/md-2-big-Data.db": bad,
+		},
+	}
+	if err := client.PutManifest(ctx, identity, m); err != nil {
+		t.Fatal(err)
+	}
+
+	v := verifier{client: client}
+	report, err := v.run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Scrubbed != 0 || len(report.Problems) != 0 {
+		t.Fatalf("unexpected report without scrubbing %+v", report)
+	}
+
+	v.scrubFraction = 1
+	v.scrubLimiter = newRateLimiter(1 << 20)
+	report, err = v.run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Scrubbed != 2 || report.UnrestorableManifests != 1 || len(report.Problems) != 1 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if problem := report.Problems[0]; problem.Problem != ProblemCorrupt || problem.Path != "ks/t-1/md-2-big-Data.db" {
+		t.Fatalf("unexpected problem %+v", problem)
+	}
+}
+
+func blobKey(digests digest.ForRestore) string {
+	encoded := digests.URLSafe()
+	return fmt.Sprintf("files/blake2b/%s/%s/%s", encoded[0:1], encoded[1:2], encoded[2:])
+}
+
+func TestScrubEncrypted(t *testing.T) {
+	ctx := context.Background()
+	backend := bucket.NewMemoryBackend()
+	identity := manifests.NodeIdentity{Cluster: "test-cluster", Hostname: "test-host"}
+
+	master := make([]byte, 32)
+	if _, err := rand.Read(master); err != nil {
+		t.Fatal(err)
+	}
+	key, err := envelope.NewKey(master)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := bucket.NewClient(backend, "")
+	client.EnableEncryption(key, "")
+	good := putTestBlob(t, client, "good")
+	swapped := putTestBlob(t, client, "swapped")
+	truncated := putTestBlob(t, client, "truncated")
+
+	// A blob replaced by another one encrypted with the same key.
+	goodCiphertext, err := backend.GetDocument(ctx, blobKey(good))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.PutDocument(ctx, blobKey(swapped), goodCiphertext); err != nil {
+		t.Fatal(err)
+	}
+	// A blob cut off part way through its header.
+	truncatedCiphertext, err := backend.GetDocument(ctx, blobKey(truncated))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.PutDocument(ctx, blobKey(truncated), truncatedCiphertext[:envelope.HeaderLength/2]); err != nil {
+		t.Fatal(err)
+	}
+
+	m := manifests.Manifest{
+		Time:         1000,
+		ManifestType: manifests.ManifestTypeSnapshot,
+		DataFiles: map[string]digest.ForRestore{
+			"ks/t-1/md-1-big-Data.db": good,
+			"ks/t-1/md-2-big-Data.db": swapped,
+			"ks/t-1/md-3-big-Data.db": truncated,
+		},
+	}
+	if err := client.PutManifest(ctx, identity, m); err != nil {
+		t.Fatal(err)
+	}
+
+	v := verifier{
+		client:        client,
+		scrubFraction: 1,
+	}
+	report, err := v.run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Scrubbed != 3 || len(report.Problems) != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	corrupt := make(map[string]bool)
+	for _, problem := range report.Problems {
+		corrupt[problem.Path] = problem.Problem == ProblemCorrupt
+	}
+	if !corrupt["ks/t-1/md-2-big-Data.db"] || !corrupt["ks/t-1/md-3-big-Data.db"] {
+		t.Fatalf("unexpected problems %+v", report.Problems)
+	}
+}
+
+func TestVerifyLength(t *testing.T) {
+	ctx := context.Background()
+	client := bucket.NewClient(bucket.NewMemoryBackend(), "")
+	identity := manifests.NodeIdentity{Cluster: "test-cluster", Hostname: "test-host"}
+
+	blob := putTestBlob(t, client, "abc")
+	m := manifests.Manifest{
+		Time:         1000,
+		ManifestType: manifests.ManifestTypeSnapshot,
+		DataFiles: map[string]digest.ForRestore{
+			"ks/t-1/md-1-big-Data.db": blob,
+		},
+		DataFileInfo: map[string]manifests.FileInfo{
+			"ks/t-1/md-1-big-Data.db": {Length: 4},
+		},
+	}
+	if err := client.PutManifest(ctx, identity, m); err != nil {
+		t.Fatal(err)
+	}
+
+	v := verifier{client: client}
+	report, err := v.run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Problems) != 1 || report.Problems[0].Problem != ProblemWrongLength {
+		t.Fatalf("unexpected report %+v", report)
+	}
+}
+
+func TestVerifyMixedEncryption(t *testing.T) {
+	ctx := context.Background()
+	backend := bucket.NewMemoryBackend()
+	identity := manifests.NodeIdentity{Cluster: "test-cluster", Hostname: "test-host"}
+
+	// Uploaded before encryption was enabled.
+	plaintext := putTestBlob(t, bucket.NewClient(backend, ""), "plaintext")
+
+	master := make([]byte, 32)
+	if _, err := rand.Read(master); err != nil {
+		t.Fatal(err)
+	}
+	key, err := envelope.NewKey(master)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := bucket.NewClient(backend, "")
+	client.EnableEncryption(key, "")
+	encrypted := putTestBlob(t, client, "encrypted")
+
+	m := manifests.Manifest{
+		Time:         1000,
+		ManifestType: manifests.ManifestTypeSnapshot,
+		DataFiles: map[string]digest.ForRestore{
+			"ks/t-1/md-1-big-Data.db": plaintext,
+			"ks/t-1/md-2-big-Data.db": encrypted,
+		},
+		DataFileInfo: map[string]manifests.FileInfo{
+			"ks/t-1/md-1-big-Data.db": {Length: int64(len("plaintext"))},
+			"ks/t-1/md-2-big-Data.db": {Length: int64(len("encrypted"))},
+		},
+	}
+	if err := client.PutManifest(ctx, identity, m); err != nil {
+		t.Fatal(err)
+	}
+
+	v := verifier{
+		client:        client,
+		scrubFraction: 1,
+		scrubLimiter:  newRateLimiter(1 << 20),
+	}
+	report, err := v.run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Scrubbed != 2 || len(report.Problems) != 0 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+}
+
+func TestWriteMetricsTextfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	unrestorableManifests.Set(2)
+	name := filepath.Join(dir, "cassandrabackup_verify.prom")
+	if err := writeMetricsTextfile(name); err != nil {
+		t.Fatal(err)
+	}
+	contents, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(contents)
+	if !strings.Contains(text, "\ncassandrabackup_verify_unrestorable_manifests 2\n") {
+		t.Fatalf("missing gauge:\n%s", text)
+	}
+	if strings.Contains(text, "go_goroutines") {
+		t.Fatalf("unexpected runtime metrics:\n%s", text)
+	}
+
+	names, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 {
+		t.Fatalf("unexpected files: %v", names)
+	}
+}
@@ -275,5 +275,5 @@ func (c Config) getDirectoryMode() os.FileMode {
 type InvalidName string
 
 func (e InvalidName) Error() string {
-	return fmt.Sprintf("writefile: invalid name: %q", e)
+	return fmt.Sprintf("writefile: invalid name: %q", string(e))
 }